@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ebadidev/arch-node/internal/backup"
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// passphraseEnv is read when the passphrase flag is not given, to keep it out of the process list.
+const passphraseEnv = "ARCH_NODE_BACKUP_PASSPHRASE"
+
+func passphrase(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	return os.Getenv(passphraseEnv)
+}
+
+func init() {
+	var backupPassphrase string
+	backupCmd := &cobra.Command{
+		Use:   "backup [file]",
+		Short: "Write a backup of the node state",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			path := fmt.Sprintf("arch-node-%s.backup", time.Now().Format("20060102-150405"))
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			c := config.New()
+			if err := c.Init(); err != nil {
+				return err
+			}
+
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			if err = backup.Write(file, c, passphrase(backupPassphrase)); err != nil {
+				_ = file.Close()
+				_ = os.Remove(path)
+				return err
+			}
+			if err = file.Close(); err != nil {
+				return err
+			}
+
+			fmt.Println("Backup written to", path)
+			return nil
+		},
+	}
+	backupCmd.Flags().StringVarP(&backupPassphrase, "passphrase", "p", "", "encrypt the backup (or set "+passphraseEnv+")")
+	rootCmd.AddCommand(backupCmd)
+}
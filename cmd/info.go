@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/internal/utils"
+	"github.com/ebadidev/arch-node/pkg/certificate"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "info",
+		Short: "Show the node connection details",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			c := config.New()
+			if err := c.Init(); err != nil {
+				return err
+			}
+
+			if !utils.FileExist(database.Path) {
+				fmt.Println("The app is not ready yet. Please try again...")
+				return nil
+			}
+			d := database.New(nil)
+			if err := d.Load(); err != nil {
+				return err
+			}
+			content, err := json.Marshal(d.Data)
+			if err != nil {
+				return err
+			}
+			fmt.Println("DB:", string(content))
+
+			if utils.FileExist(config.HttpClientCertPath) {
+				fingerprint, err := certificate.Fingerprint(config.HttpClientCertPath)
+				if err != nil {
+					return err
+				}
+				fmt.Println("Client Certificate (SHA-256):", fingerprint)
+			}
+
+			certFile, _ := c.HttpTlsFiles()
+			if certFile == "" {
+				fmt.Println("TLS: off")
+				return nil
+			}
+			fmt.Println("TLS:", c.HttpServer.Tls.Mode)
+			if !utils.FileExist(certFile) {
+				fmt.Println("Fingerprint: (not generated yet, start the node first)")
+				return nil
+			}
+			fingerprint, err := certificate.Fingerprint(certFile)
+			if err != nil {
+				return err
+			}
+			fmt.Println("Fingerprint (SHA-256):", fingerprint)
+
+			return nil
+		},
+	})
+}
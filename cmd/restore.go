@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ebadidev/arch-node/internal/backup"
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var restorePassphrase string
+	var force bool
+	restoreCmd := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Restore the node state from a backup, the node must be stopped",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if !force && utils.FileExist(database.Path) {
+				d := database.New(nil)
+				if err := d.Load(); err == nil && !utils.PortFree(d.Data.Settings.HttpPort) {
+					return fmt.Errorf("the node seems to be running on port %d, stop it first or use --force", d.Data.Settings.HttpPort)
+				}
+			}
+
+			c := config.New()
+			if err := c.Init(); err != nil {
+				return err
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = file.Close()
+			}()
+
+			manifest, err := backup.Restore(file, c, passphrase(restorePassphrase))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Restored %d files from a backup of %s (%s)\n",
+				len(manifest.Files), manifest.AppVersion, time.Unix(manifest.CreatedAt, 0).Format(time.RFC3339))
+			for _, f := range manifest.Files {
+				fmt.Println(" -", f.Path)
+			}
+			return nil
+		},
+	}
+	restoreCmd.Flags().StringVarP(&restorePassphrase, "passphrase", "p", "", "decrypt the backup (or set "+passphraseEnv+")")
+	restoreCmd.Flags().BoolVar(&force, "force", false, "restore even if the node seems to be running")
+	rootCmd.AddCommand(restoreCmd)
+}
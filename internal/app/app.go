@@ -2,15 +2,21 @@ package app
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/ebadidev/arch-node/internal/config"
 	"github.com/ebadidev/arch-node/internal/coordinator"
 	"github.com/ebadidev/arch-node/internal/database"
 	"github.com/ebadidev/arch-node/internal/http/server"
+	"github.com/ebadidev/arch-node/internal/utils"
+	"github.com/ebadidev/arch-node/pkg/audit"
+	"github.com/ebadidev/arch-node/pkg/certificate"
+	"github.com/ebadidev/arch-node/pkg/geodata"
 	"github.com/ebadidev/arch-node/pkg/http/client"
 	"github.com/ebadidev/arch-node/pkg/logger"
 	"github.com/ebadidev/arch-node/pkg/xray"
@@ -18,16 +24,19 @@ import (
 )
 
 type App struct {
-	Context    context.Context
-	Cancel     context.CancelFunc
-	Shutdown   chan struct{}
-	Config     *config.Config
-	Logger     *logger.Logger
-	HttpServer *server.Server
-	HttpClient *client.Client
-	Xray       *xray.Xray
-	Syncer     *coordinator.Coordinator
-	Database   *database.Database
+	Context           context.Context
+	Cancel            context.CancelFunc
+	Shutdown          chan struct{}
+	Config            *config.Config
+	Logger            *logger.Logger
+	HttpServer        *server.Server
+	HttpClient        *client.Client
+	ClientCertificate *certificate.Reloader
+	Xray              *xray.Xray
+	Geodata           *geodata.Manager
+	Syncer            *coordinator.Coordinator
+	Database          *database.Database
+	Audit             *audit.Log
 }
 
 func New() (a *App, err error) {
@@ -45,10 +54,18 @@ func New() (a *App, err error) {
 	}
 
 	a.Xray = xray.New(a.Context, a.Logger, config.XrayLogLevel, config.XrayConfigPath, config.XrayBinaryPath())
+	a.Geodata = geodata.New(config.XrayAssetPath(), &http.Client{Timeout: config.XrayGeodataTimeout})
 	a.Database = database.New(a.Logger)
-	a.HttpServer = server.New(a.Config, a.Logger, a.Xray, a.Database)
-	a.HttpClient = client.New(config.HttpTimeout, config.AppName, config.AppVersion)
-	a.Syncer = coordinator.New(a.Context, a.Logger, a.Config, a.Database, a.HttpClient, a.Xray)
+	a.Audit = audit.New(config.AuditLogPath)
+	a.HttpServer = server.New(a.Config, a.Logger, a.Xray, a.Geodata, a.Database, a.Audit, a.Cancel)
+	a.ClientCertificate = certificate.NewReloader(a.Logger, config.HttpClientCertPath, config.HttpClientKeyPath)
+	a.HttpClient = client.New(
+		config.HttpTimeout, config.AppName, config.AppVersion, a.ClientCertificate.GetClientCertificate,
+		func() string {
+			return a.Database.Settings().HttpSigningSecret
+		},
+	)
+	a.Syncer = coordinator.New(a.Context, a.Logger, a.Config, a.Database, a.HttpClient, a.Xray, a.Audit)
 	a.Logger.Debug("app: constructed successfully")
 
 	a.startSignalListener()
@@ -60,6 +77,9 @@ func (a *App) Start() error {
 	if err := a.Database.Init(); err != nil {
 		return errors.WithStack(err)
 	}
+	if err := a.initClientCertificate(); err != nil {
+		return errors.WithStack(err)
+	}
 	if err := a.Xray.Init(); err != nil {
 		return errors.WithStack(err)
 	}
@@ -67,12 +87,28 @@ func (a *App) Start() error {
 		return errors.WithStack(err)
 	}
 	a.Syncer.Run()
-	a.HttpServer.Run()
+	if err := a.HttpServer.Run(); err != nil {
+		return errors.WithStack(err)
+	}
 
 	a.Logger.Info("app: started successfully")
 	return nil
 }
 
+// initClientCertificate loads the certificate presented to the manager, generating a self-signed one on first start.
+func (a *App) initClientCertificate() error {
+	if !utils.FileExist(config.HttpClientCertPath) {
+		a.Logger.Info("app: generating client certificate...")
+		err := certificate.GenerateSelfSigned(
+			config.HttpClientCertPath, config.HttpClientKeyPath, config.AppName, nil, 10*365*24*time.Hour,
+		)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(a.ClientCertificate.Init())
+}
+
 func (a *App) startSignalListener() {
 	go func() {
 		signalChannel := make(chan os.Signal, 2)
@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/internal/utils"
+	"github.com/ebadidev/arch-node/pkg/backup"
+	"github.com/ebadidev/arch-node/pkg/xray"
+)
+
+// The certificate and key of the TLS "file" mode are archived under these names, as they can be anywhere on the host.
+// They are restored to the paths configured on the restoring node.
+const (
+	tlsFileCertName = "tls/file.crt"
+	tlsFileKeyName  = "tls/file.key"
+)
+
+// keyPairs maps the certificates in backups to their private keys.
+var keyPairs = map[string]string{
+	config.HttpTlsCertPath:    config.HttpTlsKeyPath,
+	config.HttpClientCertPath: config.HttpClientKeyPath,
+	tlsFileCertName:           tlsFileKeyName,
+}
+
+// paths lists the node state files included in backups.
+var paths = []string{
+	database.Path,
+	config.XrayConfigPath,
+	config.AuditLogPath,
+	config.HttpTlsCertPath,
+	config.HttpTlsKeyPath,
+	config.HttpClientCertPath,
+	config.HttpClientKeyPath,
+}
+
+// validators holds the check applied to each file included in backups before restoring it, files of other paths are rejected.
+var validators = map[string]func([]byte) error{
+	database.Path:             validateDatabase,
+	config.XrayConfigPath:     validateXrayConfig,
+	config.AuditLogPath:       nil,
+	config.HttpTlsCertPath:    pemValidator("CERTIFICATE"),
+	config.HttpTlsKeyPath:     pemValidator("PRIVATE KEY"),
+	config.HttpClientCertPath: pemValidator("CERTIFICATE"),
+	config.HttpClientKeyPath:  pemValidator("PRIVATE KEY"),
+	tlsFileCertName:           pemValidator("CERTIFICATE"),
+	tlsFileKeyName:            pemValidator("PRIVATE KEY"),
+}
+
+// Write writes a backup of the node state files, encrypted when a passphrase is given.
+// In the TLS "file" mode, the configured certificate and key are included, the node cannot start without them.
+func Write(w io.Writer, c *config.Config, passphrase string) error {
+	sources := backup.Sources(paths...)
+	if c.HttpServer.Tls.Mode == "file" {
+		certFile, keyFile := c.HttpTlsFiles()
+		for _, s := range []*backup.Source{{Name: tlsFileCertName, Path: certFile}, {Name: tlsFileKeyName, Path: keyFile}} {
+			if !utils.FileExist(s.Path) {
+				return errors.Errorf("the TLS file mode is set but %s cannot be found", s.Path)
+			}
+			sources = append(sources, s)
+		}
+	}
+	return errors.WithStack(backup.Write(w, sources, config.AppVersion, passphrase))
+}
+
+// Restore reads and validates a backup, then replaces the node state files with its files.
+// The TLS "file" mode certificate and key are restored to the paths configured in c, which must use this mode too.
+// Nothing is replaced when any part of the backup is invalid.
+func Restore(r io.Reader, c *config.Config, passphrase string) (*backup.Manifest, error) {
+	a, err := backup.Read(r, passphrase)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = a.Validate(validators); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for certPath, keyPath := range keyPairs {
+		certContent, hasCert := a.Files[certPath]
+		keyContent, hasKey := a.Files[keyPath]
+		if hasCert != hasKey {
+			return nil, errors.Errorf("%s and %s must be restored together", certPath, keyPath)
+		}
+		if !hasCert {
+			continue
+		}
+		if _, err = tls.X509KeyPair(certContent, keyContent); err != nil {
+			return nil, errors.Wrapf(err, "invalid key pair %s", certPath)
+		}
+	}
+
+	destinations := map[string]string{}
+	if _, found := a.Files[tlsFileCertName]; found {
+		if c.HttpServer.Tls.Mode != "file" {
+			return nil, errors.New("the backup has the certificate of the TLS file mode, " +
+				"set http_server.tls.mode to file with its cert_file and key_file in configs/main.json first")
+		}
+		destinations[tlsFileCertName], destinations[tlsFileKeyName] = c.HttpTlsFiles()
+	}
+
+	return a.Manifest, errors.WithStack(a.Restore(destinations))
+}
+
+func validateDatabase(content []byte) error {
+	var data database.Data
+	if err := json.Unmarshal(content, &data); err != nil {
+		return errors.WithStack(err)
+	}
+	if data.Settings == nil {
+		return errors.New("settings not found")
+	}
+	return errors.WithStack(data.Validate())
+}
+
+func validateXrayConfig(content []byte) error {
+	var c xray.Config
+	if err := json.Unmarshal(content, &c); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(c.Validate())
+}
+
+// pemValidator checks the content is PEM whose first block type ends with the given suffix.
+func pemValidator(typeSuffix string) func([]byte) error {
+	return func(content []byte) error {
+		block, _ := pem.Decode(content)
+		if block == nil {
+			return errors.New("no PEM data found")
+		}
+		if !strings.HasSuffix(block.Type, typeSuffix) {
+			return errors.Errorf("unexpected PEM block %s", block.Type)
+		}
+		return nil
+	}
+}
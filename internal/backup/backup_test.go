@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/pkg/certificate"
+)
+
+func newFileModeConfig(certFile, keyFile string) *config.Config {
+	c := config.New()
+	c.HttpServer.Tls.Mode = "file"
+	c.HttpServer.Tls.CertFile, c.HttpServer.Tls.KeyFile = certFile, keyFile
+	return c
+}
+
+func TestTlsFileMode(t *testing.T) {
+	t.Chdir(t.TempDir())
+	hostDir := t.TempDir()
+	certFile, keyFile := filepath.Join(hostDir, "node.crt"), filepath.Join(hostDir, "node.key")
+
+	c := newFileModeConfig(certFile, keyFile)
+	var buffer bytes.Buffer
+	if err := Write(&buffer, c, ""); err == nil {
+		t.Error("Expected error when the file mode certificate is missing")
+	}
+
+	if err := certificate.GenerateSelfSigned(certFile, keyFile, "node", []string{"localhost"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	buffer.Reset()
+	if err := Write(&buffer, c, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content := buffer.Bytes()
+
+	off := config.New()
+	off.HttpServer.Tls.Mode = "off"
+	if _, err := Restore(bytes.NewReader(content), off, ""); err == nil {
+		t.Error("Expected error when restoring the file mode certificate on a node without the file mode")
+	}
+
+	newDir := t.TempDir()
+	restored := newFileModeConfig(filepath.Join(newDir, "tls.crt"), filepath.Join(newDir, "tls.key"))
+	if _, err := Restore(bytes.NewReader(content), restored, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for source, destination := range map[string]string{certFile: restored.HttpServer.Tls.CertFile, keyFile: restored.HttpServer.Tls.KeyFile} {
+		expected, _ := os.ReadFile(source)
+		actual, err := os.ReadFile(destination)
+		if err != nil || !bytes.Equal(expected, actual) {
+			t.Errorf("Expected %s to be restored to %s", source, destination)
+		}
+	}
+	if _, err := certificate.Load(restored.HttpTlsFiles()); err != nil {
+		t.Errorf("Expected the restored key pair to load, got %v", err)
+	}
+}
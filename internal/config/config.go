@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/ebadidev/arch-node/internal/utils"
@@ -17,11 +20,30 @@ const configPath = "configs/main.json"
 const AppName = "Arch-Node"
 const AppVersion = "v25.8.21"
 
+const StoragePath = "storage"
+
 const XrayConfigPath = "storage/app/xray.json"
 const XrayLogLevel = "debug"
 
+const AuditLogPath = "storage/database/audit.jsonl"
+
+const XrayGeodataTimeout = 5 * time.Minute
+
 const HttpTimeout = 20
 
+const HttpAuthMaxFailures = 5
+const HttpAuthFailureWindow = 10 * time.Minute
+const HttpAuthBanDuration = 30 * time.Minute
+
+const HttpSignatureMaxSkew = 5 * time.Minute
+const HttpSignatureNonceCapacity = 100000
+
+const HttpTlsCertPath = "storage/app/http.crt"
+const HttpTlsKeyPath = "storage/app/http.key"
+
+const HttpClientCertPath = "storage/app/client.crt"
+const HttpClientKeyPath = "storage/app/client.key"
+
 var xrayBinaryPaths = map[string]string{
 	"darwin": "third_party/xray-macos-arm64/xray",
 	"linux":  "third_party/xray-linux-64/xray",
@@ -34,11 +56,60 @@ func XrayBinaryPath() string {
 	return xrayBinaryPaths["linux"]
 }
 
+// XrayAssetPath returns the directory of the Xray data files (geoip.dat and geosite.dat), next to the binary.
+func XrayAssetPath() string {
+	return filepath.Dir(XrayBinaryPath())
+}
+
 type Config struct {
 	Logger struct {
 		Level  string `json:"level" validate:"required,oneof=debug info warn error"`
 		Format string `json:"format" validate:"required,oneof='2006-01-02 15:04:05.000'"`
 	} `json:"logger" validate:"required"`
+	HttpServer struct {
+		Tls struct {
+			Mode     string `json:"mode" validate:"required,oneof=off self-signed file"`
+			CertFile string `json:"cert_file" validate:"required_if=Mode file"`
+			KeyFile  string `json:"key_file" validate:"required_if=Mode file"`
+		} `json:"tls" validate:"required"`
+	} `json:"http_server" validate:"required"`
+	Xray struct {
+		GeodataMirror string `json:"geodata_mirror" validate:"required,url"`
+	} `json:"xray" validate:"required"`
+
+	// locker guards the settings that a reload changes while the node is running.
+	locker *sync.RWMutex
+}
+
+// GeodataMirror returns the mirror the data files are downloaded from.
+func (c *Config) GeodataMirror() string {
+	c.locker.RLock()
+	defer c.locker.RUnlock()
+
+	return c.Xray.GeodataMirror
+}
+
+// Reload applies the logger level and the geodata mirror of the given fresh config, the settings that can change while
+// running. It reports whether the other settings differ, which needs a node restart.
+func (c *Config) Reload(fresh *Config) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	c.Logger.Level = fresh.Logger.Level
+	c.Xray.GeodataMirror = fresh.Xray.GeodataMirror
+	return fresh.Logger.Format != c.Logger.Format || fresh.HttpServer != c.HttpServer
+}
+
+// HttpTlsFiles returns the certificate and key paths of the HTTP server, or empty strings when TLS is off.
+func (c *Config) HttpTlsFiles() (string, string) {
+	switch c.HttpServer.Tls.Mode {
+	case "self-signed":
+		return HttpTlsCertPath, HttpTlsKeyPath
+	case "file":
+		return c.HttpServer.Tls.CertFile, c.HttpServer.Tls.KeyFile
+	default:
+		return "", ""
+	}
 }
 
 func (c *Config) toString() (string, error) {
@@ -79,5 +150,5 @@ func (c *Config) Init() (err error) {
 }
 
 func New() *Config {
-	return &Config{}
+	return &Config{locker: &sync.RWMutex{}}
 }
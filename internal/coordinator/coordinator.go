@@ -9,10 +9,12 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/ebadidev/arch-node/internal/config"
 	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/pkg/audit"
 	"github.com/ebadidev/arch-node/pkg/http/client"
 	"github.com/ebadidev/arch-node/pkg/logger"
 	"github.com/ebadidev/arch-node/pkg/worker"
 	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/gommon/random"
 	"go.uber.org/zap"
 )
 
@@ -23,6 +25,7 @@ type Coordinator struct {
 	d       *database.Database
 	xray    *xray.Xray
 	client  *client.Client
+	audit   *audit.Log
 }
 
 func (c *Coordinator) Run() {
@@ -38,28 +41,44 @@ func (c *Coordinator) Run() {
 	}).Start()
 }
 
+// Sync fetches the Xray config from the manager and applies it when it changed.
+// Each sync has a request ID, sent to the manager and added to the logs, including those of the Xray restart.
 func (c *Coordinator) Sync() error {
 	if c.d.Data.Manager == nil {
 		return nil
 	}
 
-	remoteConfig, err := c.fetchConfig(c.d.Data.Manager)
+	id := random.String(32)
+	l := c.l.With(zap.String("request_id", id))
+
+	remoteConfig, err := c.fetchConfig(client.WithRequestId(c.context, id), c.d.Data.Manager)
 	if err != nil {
-		return errors.WithStack(err)
+		return errors.Wrapf(err, "request %s", id)
 	}
 
-	if !c.xray.Config().Equals(remoteConfig) {
-		c.l.Info("coordinator: updating xray config...")
-		c.xray.SetConfig(remoteConfig)
-		go c.xray.Restart()
+	if c.xray.SetConfig(remoteConfig) {
+		l.Info("coordinator: updating xray config...")
+		go c.xray.Restart(l)
+
+		err = c.audit.Record(&audit.Entry{
+			Source:    "coordinator",
+			Path:      c.d.Data.Manager.Url,
+			Token:     "manager",
+			RequestId: id,
+			Summary:   "xray config synced from manager: " + remoteConfig.Summary(),
+			Result:    "success",
+		})
+		if err != nil {
+			l.Error("coordinator: cannot record audit", zap.Error(errors.WithStack(err)))
+		}
 	}
 
 	return nil
 }
 
-func (c *Coordinator) fetchConfig(manager *database.Manager) (*xray.Config, error) {
+func (c *Coordinator) fetchConfig(ctx context.Context, manager *database.Manager) (*xray.Config, error) {
 	url := fmt.Sprintf("%s/configs", manager.Url)
-	response, err := c.client.Do("GET", url, manager.Token, nil)
+	response, err := c.client.Do(ctx, "GET", url, manager.Token, nil)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -79,6 +98,7 @@ func New(
 	d *database.Database,
 	client *client.Client,
 	xray *xray.Xray,
+	audit *audit.Log,
 ) *Coordinator {
 	return &Coordinator{
 		l:       l,
@@ -87,5 +107,6 @@ func New(
 		d:       d,
 		client:  client,
 		xray:    xray,
+		audit:   audit,
 	}
 }
@@ -0,0 +1,101 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/pkg/audit"
+	"github.com/ebadidev/arch-node/pkg/http/client"
+	"github.com/ebadidev/arch-node/pkg/logger"
+	"github.com/ebadidev/arch-node/pkg/xray"
+)
+
+// newTestXray creates a core running a fake binary that waits until it is killed, and writing its config in a temp directory.
+func newTestXray(t *testing.T, l *logger.Logger) (*xray.Xray, string) {
+	root := t.TempDir()
+	binary := filepath.Join(root, "xray")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\nexec sleep 60\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(root, "xray.json")
+	x := xray.New(context.Background(), l, "info", configPath, binary)
+	t.Cleanup(func() {
+		_ = x.Close()
+	})
+	return x, configPath
+}
+
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("storage/logs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	l := logger.New("error", "2006-01-02 15:04:05", make(chan struct{}, 10))
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestSyncEnablesObservatoryService(t *testing.T) {
+	l := newTestLogger(t)
+
+	remote := xray.NewConfig("info")
+	remote.Observatory = &xray.Observatory{SubjectSelector: []string{"relay-"}, ProbeInterval: "1m"}
+	content, err := json.Marshal(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer manager.Close()
+
+	d := database.New(l)
+	d.Data.Manager = &database.Manager{Url: manager.URL, Token: "manager-token"}
+	x, configPath := newTestXray(t, l)
+	log := audit.New(filepath.Join(t.TempDir(), "audit.log"))
+	c := New(context.Background(), l, config.New(), d, client.New(5, "Arch-Node", "test", nil, nil), x, log)
+
+	if err = c.Sync(); err != nil {
+		t.Fatalf("Unexpected sync error: %v", err)
+	}
+	if !slices.Contains(x.Config().API.Services, xray.ObservatoryService) {
+		t.Errorf("Expected the synced config to have the observatory service, got %v", x.Config().API.Services)
+	}
+
+	// The core runs the saved file, wait for the restart to write it.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		saved, _ := os.ReadFile(configPath)
+		if strings.Contains(string(saved), xray.ObservatoryService) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the saved config to have the observatory service, got %s", saved)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// The manager copy has no observatory service, syncing it again must not be taken as a change.
+	if err = c.Sync(); err != nil {
+		t.Fatalf("Unexpected sync error: %v", err)
+	}
+	entries, err := log.Query(time.Time{}, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected a single config update, got %d", len(entries))
+	}
+}
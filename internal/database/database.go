@@ -1,13 +1,16 @@
 package database
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"math/rand"
+	"net"
 	"os"
 	"sync"
 
 	"github.com/cockroachdb/errors"
 	"github.com/ebadidev/arch-node/internal/utils"
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
 	"github.com/ebadidev/arch-node/pkg/logger"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/gommon/random"
@@ -20,10 +23,16 @@ type Data struct {
 	Manager  *Manager  `json:"manager"`
 }
 
+// Database holds the node data. The settings are replaced as a whole by UpdateSettings and never modified in place,
+// so the requests read them through Settings without holding the lock while they are updated.
 type Database struct {
 	l      *logger.Logger
-	locker *sync.Mutex
+	locker *sync.RWMutex
 	Data   *Data
+
+	allowedIps     []*net.IPNet
+	trustedProxies []*net.IPNet
+	clientCaPool   *x509.CertPool
 }
 
 func (d *Database) Init() error {
@@ -55,9 +64,105 @@ func (d *Database) Load() error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	if err = d.Data.Validate(); err != nil {
+		return errors.WithStack(err)
+	}
 
-	err = validator.New().Struct(d)
-	return errors.WithStack(err)
+	return errors.WithStack(d.parseSettings(d.Data.Settings))
+}
+
+// parseSettings parses the networks and the manager CA of the given settings once, instead of on every request.
+func (d *Database) parseSettings(s *Settings) error {
+	allowedIps, err := middleware.ParseNetworks(s.HttpAllowedIps)
+	if err != nil {
+		return errors.Wrap(err, "invalid allowed ips")
+	}
+	trustedProxies, err := middleware.ParseNetworks(s.HttpTrustedProxies)
+	if err != nil {
+		return errors.Wrap(err, "invalid trusted proxies")
+	}
+	clientCaPool, err := s.ClientCaPool()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	d.allowedIps, d.trustedProxies, d.clientCaPool = allowedIps, trustedProxies, clientCaPool
+	return nil
+}
+
+// Settings returns the current settings, which must not be modified.
+func (d *Database) Settings() *Settings {
+	d.locker.RLock()
+	defer d.locker.RUnlock()
+
+	return d.Data.Settings
+}
+
+// AllowedIps returns the parsed networks of the allowlist, empty when every client is allowed.
+func (d *Database) AllowedIps() []*net.IPNet {
+	d.locker.RLock()
+	defer d.locker.RUnlock()
+
+	return d.allowedIps
+}
+
+// TrustedProxies returns the parsed networks of the proxies trusted to set X-Forwarded-For.
+func (d *Database) TrustedProxies() []*net.IPNet {
+	d.locker.RLock()
+	defer d.locker.RUnlock()
+
+	return d.trustedProxies
+}
+
+// ClientCaPool returns the parsed manager CA, or nil when mutual TLS is disabled.
+func (d *Database) ClientCaPool() *x509.CertPool {
+	d.locker.RLock()
+	defer d.locker.RUnlock()
+
+	return d.clientCaPool
+}
+
+// ResolveToken resolves the given token with the current settings, see Settings.ResolveToken.
+func (d *Database) ResolveToken(token string) (string, string, bool) {
+	return d.Settings().ResolveToken(token)
+}
+
+// UpdateSettings applies the given change to a copy of the settings, then saves and publishes the copy.
+// The change must replace the slices it modifies rather than write into them, as readers may still hold the old settings.
+// Nothing is changed when the change or saving fails.
+func (d *Database) UpdateSettings(change func(s *Settings) error) error {
+	d.locker.Lock()
+	defer d.locker.Unlock()
+
+	current := d.Data.Settings
+	updated := *current
+	if err := change(&updated); err != nil {
+		return errors.WithStack(err)
+	}
+
+	allowedIps, trustedProxies, clientCaPool := d.allowedIps, d.trustedProxies, d.clientCaPool
+	if err := d.parseSettings(&updated); err != nil {
+		return errors.WithStack(err)
+	}
+	d.Data.Settings = &updated
+	if err := d.Save(); err != nil {
+		d.Data.Settings = current
+		d.allowedIps, d.trustedProxies, d.clientCaPool = allowedIps, trustedProxies, clientCaPool
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Validate checks the data, including that the stored manager CA can be parsed.
+func (d *Data) Validate() error {
+	if err := validator.New().Struct(d); err != nil {
+		return errors.WithStack(err)
+	}
+	if d.Settings != nil {
+		if _, err := d.Settings.ClientCaPool(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
 }
 
 func (d *Database) Save() error {
@@ -72,7 +177,7 @@ func (d *Database) Save() error {
 
 func New(l *logger.Logger) *Database {
 	return &Database{
-		locker: &sync.Mutex{},
+		locker: &sync.RWMutex{},
 		l:      l,
 		Data: &Data{
 			Manager: nil,
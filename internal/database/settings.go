@@ -1,6 +1,88 @@
 package database
 
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/internal/utils"
+	"github.com/ebadidev/arch-node/pkg/certificate"
+)
+
+const AdminTokenName = "admin"
+const PreviousAdminTokenName = "admin-previous"
+
 type Settings struct {
-	HttpPort  int    `json:"http_port" validate:"required,min=1,max=65536"`
-	HttpToken string `json:"http_token" validate:"required,min=8,max=128"`
+	HttpPort           int            `json:"http_port" validate:"required,min=1,max=65536"`
+	HttpToken          string         `json:"http_token" validate:"required,min=8,max=128"`
+	HttpPreviousToken  *PreviousToken `json:"http_previous_token,omitempty"`
+	HttpTokens         []*Token       `json:"http_tokens,omitempty" validate:"omitempty,dive"`
+	HttpSigningSecret  string         `json:"http_signing_secret,omitempty" validate:"omitempty,min=32,max=128"`
+	HttpClientCa       string         `json:"http_client_ca,omitempty" validate:"omitempty,max=65536"`
+	HttpAllowedIps     []string       `json:"http_allowed_ips,omitempty" validate:"omitempty,dive,cidr|ip"`
+	HttpTrustedProxies []string       `json:"http_trusted_proxies,omitempty" validate:"omitempty,dive,cidr|ip"`
+	PublicIps          []string       `json:"public_ips,omitempty" validate:"omitempty,max=64,dive,ip"`
+}
+
+// PreviousToken keeps the admin token replaced by a rotation working until it expires.
+type PreviousToken struct {
+	Token     string    `json:"token" validate:"required"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Token is an additional named token with a limited scope, only its hash is stored.
+type Token struct {
+	Name      string    `json:"name" validate:"required,min=1,max=64"`
+	Hash      string    `json:"hash" validate:"required"`
+	Scope     string    `json:"scope" validate:"required,oneof=stats users admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HashToken returns the stored form of a named token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResolveToken returns the name and scope of the given token, and false when the token is unknown or expired.
+func (s *Settings) ResolveToken(token string) (string, string, bool) {
+	if utils.SecureEqual(token, s.HttpToken) {
+		return AdminTokenName, "admin", true
+	}
+	if p := s.HttpPreviousToken; p != nil && time.Now().Before(p.ExpiresAt) && utils.SecureEqual(token, p.Token) {
+		return PreviousAdminTokenName, "admin", true
+	}
+
+	hash := HashToken(token)
+	for _, t := range s.HttpTokens {
+		if utils.SecureEqual(hash, t.Hash) {
+			return t.Name, t.Scope, true
+		}
+	}
+	return "", "", false
+}
+
+// FindToken returns the named token with the given name.
+func (s *Settings) FindToken(name string) *Token {
+	for _, t := range s.HttpTokens {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// ClientCaPool returns the manager CA pool used to verify client certificates, or nil when mTLS is disabled.
+// It fails when the stored CA cannot be parsed, so mTLS is never silently turned off.
+func (s *Settings) ClientCaPool() (*x509.CertPool, error) {
+	if s.HttpClientCa == "" {
+		return nil, nil
+	}
+	pool, err := certificate.Pool(s.HttpClientCa)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid manager CA")
+	}
+	return pool, nil
 }
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveToken(t *testing.T) {
+	s := &Settings{
+		HttpToken:         "current-admin-token",
+		HttpPreviousToken: &PreviousToken{Token: "previous-admin-token", ExpiresAt: time.Now().Add(time.Hour)},
+		HttpTokens: []*Token{
+			{Name: "grafana", Hash: HashToken("stats-token"), Scope: "stats"},
+			{Name: "panel", Hash: HashToken("users-token"), Scope: "users"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		found bool
+		want  string
+		scope string
+	}{
+		{"current", "current-admin-token", true, AdminTokenName, "admin"},
+		{"previous", "previous-admin-token", true, PreviousAdminTokenName, "admin"},
+		{"hashed scoped", "stats-token", true, "grafana", "stats"},
+		{"hashed users", "users-token", true, "panel", "users"},
+		{"hash itself", HashToken("stats-token"), false, "", ""},
+		{"unknown", "unknown-token", false, "", ""},
+		{"empty", "", false, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name, scope, found := s.ResolveToken(test.token)
+			if found != test.found || name != test.want || scope != test.scope {
+				t.Errorf("Expected %q %q %v, got %q %q %v", test.want, test.scope, test.found, name, scope, found)
+			}
+		})
+	}
+
+	t.Run("expired previous", func(t *testing.T) {
+		s.HttpPreviousToken.ExpiresAt = time.Now().Add(-time.Second)
+		if _, _, found := s.ResolveToken("previous-admin-token"); found {
+			t.Error("Expected the expired previous token to be rejected")
+		}
+	})
+}
+
+func TestClientCaPool(t *testing.T) {
+	s := &Settings{}
+	if pool, err := s.ClientCaPool(); pool != nil || err != nil {
+		t.Errorf("Expected no pool without a CA, got %v %v", pool, err)
+	}
+
+	s.HttpClientCa = "-----BEGIN CERTIFICATE-----\nYWJj\n-----END CERTIFICATE-----\n"
+	if pool, err := s.ClientCaPool(); pool != nil || err == nil {
+		t.Error("Expected an error for an invalid CA")
+	}
+	if err := (&Data{Settings: &Settings{HttpPort: 1000, HttpToken: "token-123", HttpClientCa: s.HttpClientCa}}).Validate(); err == nil {
+		t.Error("Expected the data with an invalid CA to be rejected")
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Dir(Path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	d := New(nil)
+	admin := d.Settings().HttpToken
+
+	err := d.UpdateSettings(func(s *Settings) error {
+		s.HttpAllowedIps = []string{"203.0.113.0/24", "198.51.100.1"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if networks := d.AllowedIps(); len(networks) != 2 || networks[1].String() != "198.51.100.1/32" {
+		t.Errorf("Expected the allowlist to be parsed when stored, got %v", networks)
+	}
+
+	err = d.UpdateSettings(func(s *Settings) error {
+		s.HttpToken = "never-published"
+		s.HttpTrustedProxies = []string{"not an ip"}
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected error for invalid trusted proxies")
+	}
+	if d.Settings().HttpToken != admin || len(d.TrustedProxies()) != 0 {
+		t.Error("Expected a failed update to leave the settings unchanged")
+	}
+
+	// The requests resolve tokens while they are rotated, go test -race checks them.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_, _, _ = d.ResolveToken(admin)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		err = d.UpdateSettings(func(s *Settings) error {
+			s.HttpPreviousToken = &PreviousToken{Token: s.HttpToken, ExpiresAt: time.Now().Add(time.Hour)}
+			s.HttpToken = HashToken(s.HttpToken)[:32]
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	<-done
+
+	saved := New(nil)
+	if err = saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Settings().HttpToken != d.Settings().HttpToken || len(saved.AllowedIps()) != 2 {
+		t.Error("Expected the updated settings to be saved")
+	}
+}
@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/pkg/audit"
+	"github.com/labstack/echo/v4"
+)
+
+type AuditIndexRequest struct {
+	From  string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To    string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Limit string `query:"limit" validate:"omitempty,number"`
+}
+
+// AuditIndex lists the audit log entries between the optional `from` and `to` times (RFC 3339).
+func AuditIndex(a *audit.Log) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := AuditIndexRequest{
+			From:  c.QueryParam("from"),
+			To:    c.QueryParam("to"),
+			Limit: c.QueryParam("limit"),
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": "Validation error: from and to must be RFC 3339 times, limit must be a number.",
+			})
+		}
+
+		var from, to time.Time
+		if r.From != "" {
+			from, _ = time.Parse(time.RFC3339, r.From)
+		}
+		if r.To != "" {
+			to, _ = time.Parse(time.RFC3339, r.To)
+		}
+		limit := 1000
+		if r.Limit != "" {
+			limit, _ = strconv.Atoi(r.Limit)
+		}
+
+		entries, err := a.Query(from, to, limit)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"entries": entries,
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ebadidev/arch-node/internal/backup"
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+// BackupShow downloads a backup of the node state, encrypted when the X-Backup-Passphrase header is set.
+func BackupShow(cfg *config.Config) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var buffer bytes.Buffer
+		if err := backup.Write(&buffer, cfg, c.Request().Header.Get("X-Backup-Passphrase")); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": fmt.Sprintf("Cannot create the backup: %v", err.Error()),
+			})
+		}
+
+		name := fmt.Sprintf("arch-node-%s.backup", time.Now().Format("20060102-150405"))
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+		return c.Blob(http.StatusOK, echo.MIMEOctetStream, buffer.Bytes())
+	}
+}
@@ -0,0 +1,16 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+func BansIndex(bans *middleware.Banlist) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"bans": bans.List(),
+		})
+	}
+}
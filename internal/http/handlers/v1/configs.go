@@ -2,14 +2,19 @@ package v1
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/ebadidev/arch-node/internal/utils"
+	"github.com/ebadidev/arch-node/pkg/geodata"
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
+	"github.com/ebadidev/arch-node/pkg/ports"
 	"github.com/ebadidev/arch-node/pkg/xray"
 	"github.com/labstack/echo/v4"
 )
 
-func ConfigsStore(x *xray.Xray) echo.HandlerFunc {
+func ConfigsStore(x *xray.Xray, g *geodata.Manager) echo.HandlerFunc {
 	return func(c echo.Context) (err error) {
 		var config xray.Config
 		if err = c.Bind(&config); err != nil {
@@ -22,6 +27,11 @@ func ConfigsStore(x *xray.Xray) echo.HandlerFunc {
 				"message": fmt.Sprintf("Validation error: %v", err.Error()),
 			})
 		}
+		if err = g.Check(config.GeoReferences()); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
 
 		if c.Request().Header.Get("X-App-Name") != "Arch-Manager" {
 			return c.JSON(http.StatusBadRequest, map[string]string{
@@ -29,36 +39,71 @@ func ConfigsStore(x *xray.Xray) echo.HandlerFunc {
 			})
 		}
 
-		for _, i := range config.Inbounds {
-			isFree := utils.PortFree(i.Port)
-			if i.Tag != "api" && i.Tag != "remote" && !isFree {
+		for _, o := range config.Outbounds {
+			if !localSendThrough(o.SendThrough) {
 				return c.JSON(http.StatusUnprocessableEntity, map[string]string{
-					"message": fmt.Sprintf("The port '%s.%d' is already in use", i.Tag, i.Port),
+					"message": fmt.Sprintf("The sendThrough '%s.%s' is not an address of this server", o.Tag, o.SendThrough),
 				})
 			}
-			if i.Tag == "remote" && !isFree {
-				currentInbound := x.Config().FindInbound("remote")
-				if currentInbound == nil || currentInbound.Port != i.Port {
-					return c.JSON(http.StatusUnprocessableEntity, map[string]string{
-						"message": fmt.Sprintf("The port '%s.%d' is already in use", i.Tag, i.Port),
-					})
-				}
-			}
+		}
+
+		var requests []*ports.Request
+		tags := map[string]string{}
+		for _, i := range config.Inbounds {
 			if i.Tag == "api" {
 				if i.Port, err = utils.FreePort(); err != nil {
 					return c.JSON(http.StatusUnprocessableEntity, map[string]string{
 						"message": fmt.Sprintf("API inbound port failed, err: %v", err.Error()),
 					})
 				}
+				continue
+			}
+			for _, t := range i.Transports() {
+				requests = append(requests, &ports.Request{Port: i.Port, Protocol: t})
+				tags[fmt.Sprintf("%s:%d", t, i.Port)] = i.Tag
 			}
 		}
 
+		conflicts, err := checkPorts(x, requests)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": fmt.Sprintf("Cannot check the ports: %v", err.Error()),
+			})
+		}
+		if len(conflicts) > 0 {
+			held := make([]string, 0, len(conflicts))
+			for _, conflict := range conflicts {
+				tag := tags[fmt.Sprintf("%s:%d", conflict.Protocol, conflict.Port)]
+				held = append(held, fmt.Sprintf("'%s.%d/%s'", tag, conflict.Port, conflict.Protocol))
+			}
+			return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+				"message":   fmt.Sprintf("The ports %s are already in use", strings.Join(held, ", ")),
+				"conflicts": conflicts,
+			})
+		}
+
+		middleware.AuditSummary(c, "xray config stored: %s", config.Summary())
 		x.SetConfig(&config)
 
-		go x.Restart()
+		go x.Restart(middleware.RequestLogger(c))
 
 		return c.JSON(http.StatusOK, map[string]string{
 			"message": "The configs stored successfully.",
 		})
 	}
 }
+
+// localSendThrough checks the sendThrough IP is assigned to this server and the sendThrough CIDR falls within the network
+// of one of its interfaces. The other values ("origin", "srcip" and the unspecified addresses) do not pick an address.
+func localSendThrough(value string) bool {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		local, err := utils.LocalNetwork(network)
+		return err == nil && local
+	}
+	ip := net.ParseIP(value)
+	if ip == nil || ip.IsUnspecified() {
+		return true
+	}
+	local, err := utils.LocalAddress(ip)
+	return err == nil && local
+}
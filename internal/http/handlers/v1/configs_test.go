@@ -0,0 +1,112 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ebadidev/arch-node/pkg/geodata"
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+)
+
+func TestConfigsStorePortConflicts(t *testing.T) {
+	_, l := newTestNode(t)
+	x := xray.New(context.Background(), l, "info", "storage/xray.json", "missing-binary")
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = tcp.Close()
+	}()
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = udp.Close()
+	}()
+	tcpPort := tcp.Addr().(*net.TCPAddr).Port
+	udpPort := udp.LocalAddr().(*net.UDPAddr).Port
+
+	config := xray.NewConfig("info")
+	config.Inbounds = append(config.Inbounds,
+		config.MakeVlessInbound("vless", tcpPort, "b831381d-6324-4d53-ad4f-8cda48b30811", "tcp", nil),
+		config.MakeVlessInbound("kcp", udpPort, "b831381d-6324-4d53-ad4f-8cda48b30811", "kcp", &xray.StreamSettings{Network: "kcp"}),
+	)
+	body, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/configs", bytes.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	request.Header.Set("X-App-Name", "Arch-Manager")
+	recorder := httptest.NewRecorder()
+	c := echo.New().NewContext(request, recorder)
+	if err = ConfigsStore(x, geodata.New(t.TempDir(), http.DefaultClient))(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Message   string `json:"message"`
+		Conflicts []struct {
+			Port     int    `json:"port"`
+			Protocol string `json:"protocol"`
+		} `json:"conflicts"`
+	}
+	if err = json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	for _, held := range []string{fmt.Sprintf("vless.%d/tcp", tcpPort), fmt.Sprintf("kcp.%d/udp", udpPort)} {
+		if !strings.Contains(response.Message, held) {
+			t.Errorf("Expected the message to report %s, got %s", held, response.Message)
+		}
+	}
+	if len(response.Conflicts) != 2 {
+		t.Errorf("Expected 2 conflicts, got %+v", response.Conflicts)
+	}
+	if x.Config() != nil && x.Config().FindInbound("vless") != nil {
+		t.Error("Expected the conflicting config not to be stored")
+	}
+}
+
+func TestConfigsStoreSendThrough(t *testing.T) {
+	_, l := newTestNode(t)
+	x := xray.New(context.Background(), l, "info", "storage/xray.json", "missing-binary")
+
+	for _, sendThrough := range []string{"203.0.113.9", "203.0.113.0/24", "127.0.0.0/7"} {
+		t.Run(sendThrough, func(t *testing.T) {
+			config := xray.NewConfig("info")
+			config.Outbounds[0].SendThrough = sendThrough
+			body, err := json.Marshal(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/configs", bytes.NewReader(body))
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			request.Header.Set("X-App-Name", "Arch-Manager")
+			recorder := httptest.NewRecorder()
+			c := echo.New().NewContext(request, recorder)
+			if err = ConfigsStore(x, geodata.New(t.TempDir(), http.DefaultClient))(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if recorder.Code != http.StatusUnprocessableEntity || !strings.Contains(recorder.Body.String(), "not an address of this server") {
+				t.Errorf("Expected status 422 for the sendThrough %s, got %d: %s", sendThrough, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
+	"github.com/ebadidev/arch-node/pkg/logger"
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+)
+
+var busyResponse = map[string]string{
+	"message": "Another restart, reload or stop is in progress, please try again later.",
+}
+
+// XrayRestart restarts the Xray core with its current config and returns the resulting state.
+func XrayRestart(x *xray.Xray) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		middleware.AuditSummary(c, "xray core restarted")
+		if err := x.TryRestart(middleware.RequestLogger(c)); err != nil {
+			if errors.Is(err, xray.ErrBusy) {
+				return c.JSON(http.StatusConflict, busyResponse)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": fmt.Sprintf("Cannot restart the Xray core: %v", err.Error()),
+			})
+		}
+		return c.JSON(http.StatusOK, x.Status())
+	}
+}
+
+// NodeReload reads configs/main.json again and applies the logger level and the geodata mirror.
+// Changes to the logger format or the HTTP server need a node restart, which is reported in the response.
+func NodeReload(cfg *config.Config, l *logger.Logger, x *xray.Xray) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		restartRequired := false
+		fresh := config.New()
+		err := x.Exclusively(func() error {
+			if err := fresh.Init(); err != nil {
+				return err
+			}
+			if err := l.SetLevel(fresh.Logger.Level); err != nil {
+				return err
+			}
+			restartRequired = cfg.Reload(fresh)
+			return nil
+		})
+		if err != nil {
+			if errors.Is(err, xray.ErrBusy) {
+				return c.JSON(http.StatusConflict, busyResponse)
+			}
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Cannot reload the config: %v", err.Error()),
+			})
+		}
+
+		middleware.AuditSummary(c, "node config reloaded, logger level %s", fresh.Logger.Level)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"logger_level":     fresh.Logger.Level,
+			"tls_mode":         cfg.HttpServer.Tls.Mode,
+			"restart_required": restartRequired,
+		})
+	}
+}
+
+// NodeStop responds and then shuts the node down gracefully, closing the HTTP server and the Xray core.
+func NodeStop(x *xray.Xray, shutdown func()) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := x.Exclusively(func() error {
+			shutdown()
+			return nil
+		})
+		if err != nil {
+			return c.JSON(http.StatusConflict, busyResponse)
+		}
+
+		middleware.AuditSummary(c, "node stopped")
+		return c.JSON(http.StatusAccepted, map[string]string{
+			"state": "stopping",
+		})
+	}
+}
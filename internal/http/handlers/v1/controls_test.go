@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/pkg/logger"
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+)
+
+const testDefaults = `{
+  "logger": {"level": "warn", "format": "2006-01-02 15:04:05.000"},
+  "http_server": {"tls": {"mode": "off", "cert_file": "", "key_file": ""}},
+  "xray": {"geodata_mirror": "https://mirror.example.com/dat"}
+}`
+
+// newTestNode prepares the working directory of a node with the default config, and returns its config and logger.
+func newTestNode(t *testing.T) (*config.Config, *logger.Logger) {
+	t.Chdir(t.TempDir())
+	for _, dir := range []string{"configs", "storage/logs"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile("configs/main.defaults.json", []byte(testDefaults), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	if err := cfg.Init(); err != nil {
+		t.Fatal(err)
+	}
+	l := logger.New(cfg.Logger.Level, cfg.Logger.Format, make(chan struct{}, 10))
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return cfg, l
+}
+
+func TestNodeReload(t *testing.T) {
+	cfg, l := newTestNode(t)
+	x := xray.New(context.Background(), l, "info", "storage/xray.json", "missing-binary")
+	handler := NodeReload(cfg, l, x)
+
+	reload := func() map[string]interface{} {
+		recorder := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/v1/node/reload", nil), recorder)
+		if err := handler(c); err != nil {
+			t.Fatal(err)
+		}
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	main := `{"logger": {"level": "debug"}, "xray": {"geodata_mirror": "https://other.example.com/dat"}}`
+	if err := os.WriteFile("configs/main.json", []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The geodata handlers read the mirror while the reload changes it, go test -race checks them.
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = cfg.GeodataMirror()
+				_ = x.Status()
+			}
+		}
+	}()
+	response := reload()
+	close(done)
+	wg.Wait()
+
+	if response["logger_level"] != "debug" || response["restart_required"] != false {
+		t.Errorf("Unexpected response %v", response)
+	}
+	if cfg.GeodataMirror() != "https://other.example.com/dat" {
+		t.Errorf("Expected the mirror to be reloaded, got %s", cfg.GeodataMirror())
+	}
+
+	main = `{"http_server": {"tls": {"mode": "self-signed"}}}`
+	if err := os.WriteFile("configs/main.json", []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if response = reload(); response["restart_required"] != true || response["tls_mode"] != "off" {
+		t.Errorf("Expected a restart to be required for the TLS mode, got %v", response)
+	}
+}
@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/pkg/geodata"
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+)
+
+// GeodataIndex lists the installed geoip.dat and geosite.dat files with their versions and checksums.
+func GeodataIndex(g *geodata.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		files, err := g.List()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": fmt.Sprintf("Cannot read the data files: %v", err.Error()),
+			})
+		}
+		return c.JSON(http.StatusOK, files)
+	}
+}
+
+// GeodataStore installs the data file in the request body and restarts the Xray core.
+// The optional X-Geodata-Version and X-Geodata-Sha256 headers set its version and expected checksum.
+func GeodataStore(x *xray.Xray, g *geodata.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name := c.Param("name")
+		if !geodata.Valid(name) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"message": fmt.Sprintf("Unknown data file '%s'.", name),
+			})
+		}
+
+		upload, err := g.Stage(name, c.Request().Body, c.Request().Header.Get("X-Geodata-Sha256"))
+		if err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Invalid data file: %v", err.Error()),
+			})
+		}
+
+		version := c.Request().Header.Get("X-Geodata-Version")
+		if version == "" {
+			version = time.Now().UTC().Format("200601021504")
+		}
+		return installGeodata(c, x, upload, version)
+	}
+}
+
+// GeodataDownload downloads the data file from the configured mirror, installs it and restarts the Xray core.
+func GeodataDownload(cfg *config.Config, x *xray.Xray, g *geodata.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name := c.Param("name")
+		if !geodata.Valid(name) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"message": fmt.Sprintf("Unknown data file '%s'.", name),
+			})
+		}
+
+		upload, version, err := g.Download(c.Request().Context(), cfg.GeodataMirror(), name)
+		if err != nil {
+			return c.JSON(http.StatusBadGateway, map[string]string{
+				"message": fmt.Sprintf("Cannot download the data file: %v", err.Error()),
+			})
+		}
+		return installGeodata(c, x, upload, version)
+	}
+}
+
+// installGeodata swaps the staged file in while the core is locked and restarts the core,
+// unless the current config references codes the new file lacks.
+func installGeodata(c echo.Context, x *xray.Xray, upload *geodata.Upload, version string) error {
+	defer upload.Discard()
+
+	if err := upload.Check(x.Config().GeoReferences()); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+			"message": fmt.Sprintf("The current config cannot use the data file: %v", err.Error()),
+		})
+	}
+
+	var file *geodata.File
+	err := x.TryRestartAfter(middleware.RequestLogger(c), func() (err error) {
+		file, err = upload.Install(version)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, xray.ErrBusy) {
+			return c.JSON(http.StatusConflict, busyResponse)
+		}
+		if file == nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": fmt.Sprintf("Cannot install the data file: %v", err.Error()),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": fmt.Sprintf("The data file is installed but the Xray core cannot restart: %v", err.Error()),
+		})
+	}
+
+	middleware.AuditSummary(c, "%s %s installed (%s)", file.Name, file.Version, file.Sha256)
+	return c.JSON(http.StatusOK, file)
+}
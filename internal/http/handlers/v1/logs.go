@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/pkg/logger"
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type LogsStreamRequest struct {
+	Source string `validate:"required,oneof=app xray-error xray-access"`
+	Level  string `validate:"omitempty,oneof=debug info warn error"`
+}
+
+var logLevels = map[string]int{"debug": 1, "info": 2, "warn": 3, "warning": 3, "error": 4}
+
+var xrayLogLevel = regexp.MustCompile(`\[(Debug|Info|Warning|Error)]`)
+
+// LogsStream streams the app, Xray error or Xray access log as server-sent events, until the client disconnects.
+// The optional `level` and `q` query parameters filter the lines by minimum level and substring.
+func LogsStream(l *logger.Logger, x *xray.Xray) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := LogsStreamRequest{Source: c.QueryParam("source"), Level: c.QueryParam("level")}
+		if r.Source == "" {
+			r.Source = "app"
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": "Validation error: source must be app, xray-error or xray-access; level must be debug, info, warn or error.",
+			})
+		}
+		query := c.QueryParam("q")
+
+		var path string
+		if r.Source != "app" {
+			if path = x.Config().Log.Error; r.Source == "xray-access" {
+				path = x.Config().Log.Access
+			}
+			if path == "" || path == "none" {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"message": fmt.Sprintf("The %s log is disabled.", r.Source),
+				})
+			}
+		}
+
+		lines := make(chan string, 256)
+		ctx := c.Request().Context()
+
+		if r.Source == "app" {
+			entries, cancel, err := l.Subscribe(256, r.Level)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer cancel()
+			go func() {
+				for entry := range entries {
+					select {
+					case lines <- strings.TrimRight(string(entry), "\n"):
+					default:
+					}
+				}
+			}()
+		} else {
+			go func() {
+				err := logger.Tail(ctx, path, 500*time.Millisecond, func(line string) {
+					select {
+					case lines <- line:
+					default:
+					}
+				})
+				if err != nil {
+					l.Error("logs: cannot tail", zap.String("path", path), zap.Error(err))
+				}
+			}()
+		}
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Connection", "keep-alive")
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+		res.Flush()
+
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+				res.Flush()
+			case line := <-lines:
+				if !logLineMatches(r.Source, line, r.Level, query) {
+					continue
+				}
+				if _, err := fmt.Fprintf(res, "data: %s\n\n", line); err != nil {
+					return nil
+				}
+				res.Flush()
+			}
+		}
+	}
+}
+
+// logLineMatches checks the line against the minimum level and the substring filters.
+func logLineMatches(source, line, level, query string) bool {
+	if query != "" && !strings.Contains(line, query) {
+		return false
+	}
+	if level == "" {
+		return true
+	}
+
+	lineLevel := "info"
+	if source == "app" {
+		var entry struct {
+			Level string `json:"level"`
+		}
+		if json.Unmarshal([]byte(line), &entry) == nil && entry.Level != "" {
+			lineLevel = strings.ToLower(entry.Level)
+		}
+	} else if m := xrayLogLevel.FindStringSubmatch(line); m != nil {
+		lineLevel = strings.ToLower(m[1])
+	}
+
+	return logLevels[lineLevel] >= logLevels[level]
+}
@@ -0,0 +1,43 @@
+package v1
+
+import "testing"
+
+func TestLogLineMatches(t *testing.T) {
+	const appDebug = `{"level":"DEBUG","ts":"2025-01-01 00:00:00","message":"xray: connecting to api..."}`
+	const appError = `{"level":"ERROR","ts":"2025-01-01 00:00:00","message":"coordinator: cannot sync"}`
+	const xrayWarning = `2025/01/01 00:00:00 [Warning] core: Xray 25.6.8 started`
+	const xrayDebug = `2025/01/01 00:00:00 [Debug] app/dns: domain example.com`
+	const access = `2025/01/01 00:00:00 from 203.0.113.5:5000 accepted tcp:example.com:443 [vless >> out] email: alice`
+
+	tests := []struct {
+		name     string
+		source   string
+		line     string
+		level    string
+		query    string
+		expected bool
+	}{
+		{"no filter", "app", appDebug, "", "", true},
+		{"app debug at debug", "app", appDebug, "debug", "", true},
+		{"app debug at info", "app", appDebug, "info", "", false},
+		{"app error at warn", "app", appError, "warn", "", true},
+		{"app not json", "app", "panic: runtime error", "info", "", true},
+		{"app not json at warn", "app", "panic: runtime error", "warn", "", false},
+		{"xray warning at warn", "xray-error", xrayWarning, "warn", "", true},
+		{"xray warning at error", "xray-error", xrayWarning, "error", "", false},
+		{"xray debug at info", "xray-error", xrayDebug, "info", "", false},
+		{"access line as info", "xray-access", access, "info", "", true},
+		{"access line at warn", "xray-access", access, "warn", "", false},
+		{"query", "xray-access", access, "", "alice", true},
+		{"query not found", "xray-access", access, "", "bob", false},
+		{"query and level", "app", appError, "error", "sync", true},
+		{"query with wrong level", "app", appDebug, "error", "xray", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if matched := logLineMatches(test.source, test.line, test.level, test.query); matched != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, matched)
+			}
+		})
+	}
+}
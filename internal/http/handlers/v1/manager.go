@@ -6,6 +6,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
 	"github.com/labstack/echo/v4"
 )
 
@@ -29,8 +30,10 @@ func ManagerStore(d *database.Database) echo.HandlerFunc {
 		}
 
 		if r.Url == "" {
+			middleware.AuditSummary(c, "manager cleared")
 			d.Data.Manager = nil
 		} else {
+			middleware.AuditSummary(c, "manager set to %s", r.Url)
 			d.Data.Manager = &database.Manager{
 				Url:   r.Url,
 				Token: r.Token,
@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+)
+
+// OutboundsHealth reports the observatory results of the running core per outbound.
+func OutboundsHealth(x *xray.Xray) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if x.Config().Observatory == nil && x.Config().BurstObservatory == nil {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"message": "No observatory is configured.",
+			})
+		}
+
+		health, err := x.QueryObservatory()
+		if err != nil {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{
+				"message": fmt.Sprintf("Cannot query the observatory: %v", err.Error()),
+			})
+		}
+
+		return c.JSON(http.StatusOK, health)
+	}
+}
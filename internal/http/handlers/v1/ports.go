@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/pkg/ports"
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+)
+
+type PortsCheckRequest struct {
+	Ports    []*ports.Request `json:"ports" validate:"omitempty,dive"`
+	Inbounds []*xray.Inbound  `json:"inbounds" validate:"omitempty,dive"`
+}
+
+// listPorts returns the host sockets labeled by owner: the node itself, the Xray core or another process.
+func listPorts(x *xray.Xray) ([]*ports.Socket, error) {
+	sockets, err := ports.List("/proc")
+	if err != nil {
+		return nil, err
+	}
+	ports.Label(sockets, map[int]string{os.Getpid(): "node", x.Pid(): "xray"})
+	return sockets, nil
+}
+
+// checkPorts returns the requested ports held by the node or another process. Where the sockets cannot be listed, the ports
+// are probed by binding them instead, skipping the ones the running core listens on.
+func checkPorts(x *xray.Xray, requests []*ports.Request) ([]*ports.Conflict, error) {
+	sockets, err := listPorts(x)
+	if errors.Is(err, ports.ErrUnavailable) {
+		current := x.Config()
+		var probed []*ports.Request
+		for _, r := range requests {
+			if x.Pid() == 0 || current == nil || !listensOn(current, r) {
+				probed = append(probed, r)
+			}
+		}
+		return ports.Probe(probed), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ports.Check(sockets, requests, "xray"), nil
+}
+
+// listensOn reports whether an inbound of the config listens on the requested port and transport.
+func listensOn(config *xray.Config, r *ports.Request) bool {
+	for _, i := range config.Inbounds {
+		if i.Port == r.Port && slices.Contains(i.Transports(), r.Protocol) {
+			return true
+		}
+	}
+	return false
+}
+
+func PortsIndex(x *xray.Xray) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		sockets, err := listPorts(x)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": fmt.Sprintf("Cannot list the ports: %v", err.Error()),
+			})
+		}
+		return c.JSON(http.StatusOK, sockets)
+	}
+}
+
+// PortsCheck reports every requested port that is held by the node or another process.
+// Ports held by the Xray core are not conflicts, since it releases them when it restarts with a new config.
+func PortsCheck(x *xray.Xray) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var r PortsCheckRequest
+		if err := c.Bind(&r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "Cannot parse the request body.",
+			})
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+
+		requests := r.Ports
+		for _, i := range r.Inbounds {
+			for _, t := range i.Transports() {
+				requests = append(requests, &ports.Request{Port: i.Port, Protocol: t})
+			}
+		}
+
+		conflicts, err := checkPorts(x, requests)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": fmt.Sprintf("Cannot check the ports: %v", err.Error()),
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"available": len(conflicts) == 0,
+			"conflicts": conflicts,
+		})
+	}
+}
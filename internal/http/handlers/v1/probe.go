@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+)
+
+type ProbeStoreRequest struct {
+	Tag     string `json:"tag" validate:"required"`
+	Url     string `json:"url" validate:"omitempty,url,max=1024"`
+	Timeout int    `json:"timeout" validate:"omitempty,min=1,max=60"`
+}
+
+// ProbeStore sends a test HTTP request through the given outbound and reports the latency, status and error.
+func ProbeStore(x *xray.Xray) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := ProbeStoreRequest{Url: "https://www.google.com/generate_204", Timeout: 10}
+		if err := c.Bind(&r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "Cannot parse the request body.",
+			})
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+
+		if x.Config().FindOutbound(r.Tag) == nil {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"message": fmt.Sprintf("The outbound '%s' not found.", r.Tag),
+			})
+		}
+
+		middleware.AuditSummary(c, "outbound '%s' probed with %s", r.Tag, r.Url)
+		result, err := x.Probe(c.Request().Context(), r.Tag, r.Url, time.Duration(r.Timeout)*time.Second)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": fmt.Sprintf("Cannot probe the outbound: %v", err.Error()),
+			})
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,209 @@
+package v1
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/pkg/certificate"
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type SettingsClientCaStoreRequest struct {
+	Ca string `json:"ca" validate:"omitempty,max=65536"`
+}
+
+// SettingsClientCaStore stores the manager CA and enables mutual TLS, or disables it when the CA is empty.
+// The caller must present a client certificate signed by the new CA, so it cannot lock itself out.
+func SettingsClientCaStore(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var r SettingsClientCaStoreRequest
+		if err := c.Bind(&r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "Cannot parse the request body.",
+			})
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+
+		if r.Ca != "" {
+			pool, err := certificate.Pool(r.Ca)
+			if err != nil {
+				return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+					"message": fmt.Sprintf("Invalid CA: %v", err.Error()),
+				})
+			}
+
+			state := c.Request().TLS
+			if state == nil {
+				return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+					"message": "Mutual TLS requires the API to be served over TLS.",
+				})
+			}
+			if len(state.PeerCertificates) == 0 {
+				return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+					"message": "Present a client certificate signed by the new CA.",
+				})
+			}
+			_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:     pool,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			if err != nil {
+				return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+					"message": fmt.Sprintf("The client certificate is not signed by the new CA: %v", err.Error()),
+				})
+			}
+		}
+
+		middleware.AuditSummary(c, "mutual tls enabled: %v", r.Ca != "")
+		err := d.UpdateSettings(func(s *database.Settings) error {
+			s.HttpClientCa = r.Ca
+			return nil
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"mtls": r.Ca != "",
+		})
+	}
+}
+
+type SettingsAllowlistStoreRequest struct {
+	AllowedIps     []string `json:"allowed_ips" validate:"omitempty,max=256,dive,cidr|ip"`
+	TrustedProxies []string `json:"trusted_proxies" validate:"omitempty,max=256,dive,cidr|ip"`
+}
+
+func SettingsAllowlistShow(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		s := d.Settings()
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"allowed_ips":     s.HttpAllowedIps,
+			"trusted_proxies": s.HttpTrustedProxies,
+			"caller_ip":       c.RealIP(),
+		})
+	}
+}
+
+// SettingsAllowlistStore updates the allowed IPs and the trusted proxies.
+// It rejects the update if the caller itself would not be allowed anymore.
+func SettingsAllowlistStore(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var r SettingsAllowlistStoreRequest
+		if err := c.Bind(&r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "Cannot parse the request body.",
+			})
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+
+		callerIp, allowed, err := middleware.AllowsCaller(c.Request(), r.AllowedIps, r.TrustedProxies)
+		if err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+		if !allowed {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"message": fmt.Sprintf("The update would lock out the caller IP '%s'.", callerIp),
+			})
+		}
+
+		middleware.AuditSummary(c, "allowlist set to %v, trusted proxies set to %v", r.AllowedIps, r.TrustedProxies)
+		err = d.UpdateSettings(func(s *database.Settings) error {
+			s.HttpAllowedIps = r.AllowedIps
+			s.HttpTrustedProxies = r.TrustedProxies
+			return nil
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"allowed_ips":     r.AllowedIps,
+			"trusted_proxies": r.TrustedProxies,
+		})
+	}
+}
+
+type SettingsSigningStoreRequest struct {
+	Secret string `json:"secret" validate:"omitempty,min=32,max=128"`
+}
+
+// SettingsSigningStore stores the secret shared with the manager to sign requests in both directions.
+// Once stored, unsigned requests are rejected; an empty secret disables request signing.
+func SettingsSigningStore(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var r SettingsSigningStoreRequest
+		if err := c.Bind(&r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "Cannot parse the request body.",
+			})
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+
+		middleware.AuditSummary(c, "request signing enabled: %v", r.Secret != "")
+		err := d.UpdateSettings(func(s *database.Settings) error {
+			s.HttpSigningSecret = r.Secret
+			return nil
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"signing": r.Secret != "",
+		})
+	}
+}
+
+type SettingsPublicIpsStoreRequest struct {
+	PublicIps []string `json:"public_ips" validate:"omitempty,max=64,dive,ip"`
+}
+
+// SettingsPublicIpsStore stores the public IPs of the node reported by the system information,
+// the interfaces of a node behind NAT only have private addresses.
+func SettingsPublicIpsStore(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var r SettingsPublicIpsStoreRequest
+		if err := c.Bind(&r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "Cannot parse the request body.",
+			})
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+
+		middleware.AuditSummary(c, "public ips set to %v", r.PublicIps)
+		err := d.UpdateSettings(func(s *database.Settings) error {
+			s.PublicIps = r.PublicIps
+			return nil
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"public_ips": r.PublicIps,
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/pkg/sysinfo"
+	"github.com/ebadidev/arch-node/pkg/xray"
+	"github.com/labstack/echo/v4"
+)
+
+func SystemShow(d *database.Database, x *xray.Xray) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		info := sysinfo.Collect("/proc", config.StoragePath, d.Settings().PublicIps)
+		info.AppVersion = config.AppVersion
+		info.XrayVersion, _ = x.Version()
+		return c.JSON(http.StatusOK, info)
+	}
+}
@@ -0,0 +1,159 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/pkg/http/middleware"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/random"
+)
+
+var (
+	errTokenNameTaken = errors.New("token name taken")
+	errTokenNotFound  = errors.New("token not found")
+)
+
+type TokenRotateRequest struct {
+	Overlap int `json:"overlap" validate:"omitempty,min=0,max=604800"`
+}
+
+type TokenStoreRequest struct {
+	Name  string `json:"name" validate:"required,min=1,max=64,printascii,excludesall=/"`
+	Scope string `json:"scope" validate:"required,oneof=stats users admin"`
+}
+
+// TokenRotate replaces the admin token, the previous one keeps working during the overlap (in seconds, default one hour).
+func TokenRotate(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := TokenRotateRequest{Overlap: 3600}
+		if err := c.Bind(&r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "Cannot parse the request body.",
+			})
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+
+		token := random.String(32)
+		expiresAt := time.Now().Add(time.Duration(r.Overlap) * time.Second)
+		middleware.AuditSummary(c, "admin token rotated, previous one expires at %s", expiresAt.Format(time.RFC3339))
+		err := d.UpdateSettings(func(s *database.Settings) error {
+			s.HttpPreviousToken = &database.PreviousToken{Token: s.HttpToken, ExpiresAt: expiresAt}
+			s.HttpToken = token
+			return nil
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"token":                     token,
+			"previous_token_expires_at": expiresAt,
+		})
+	}
+}
+
+func TokensIndex(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		settings := d.Settings()
+		tokens := make([]map[string]interface{}, 0, len(settings.HttpTokens))
+		for _, t := range settings.HttpTokens {
+			tokens = append(tokens, map[string]interface{}{
+				"name":       t.Name,
+				"scope":      t.Scope,
+				"created_at": t.CreatedAt,
+			})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"tokens": tokens,
+		})
+	}
+}
+
+// TokensStore mints a named token, the token itself is only returned in this response.
+func TokensStore(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var r TokenStoreRequest
+		if err := c.Bind(&r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "Cannot parse the request body.",
+			})
+		}
+		if err := c.Validate(&r); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("Validation error: %v", err.Error()),
+			})
+		}
+
+		token := random.String(32)
+		t := &database.Token{
+			Name:      r.Name,
+			Hash:      database.HashToken(token),
+			Scope:     r.Scope,
+			CreatedAt: time.Now(),
+		}
+		// The name is checked in the update, so two concurrent requests cannot mint the same name.
+		err := d.UpdateSettings(func(s *database.Settings) error {
+			if r.Name == database.AdminTokenName || r.Name == database.PreviousAdminTokenName || s.FindToken(r.Name) != nil {
+				return errTokenNameTaken
+			}
+			s.HttpTokens = append(slices.Clone(s.HttpTokens), t)
+			return nil
+		})
+		if errors.Is(err, errTokenNameTaken) {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"message": fmt.Sprintf("The token name '%s' is already taken.", r.Name),
+			})
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		middleware.AuditSummary(c, "token '%s' minted with scope '%s'", t.Name, t.Scope)
+
+		return c.JSON(http.StatusCreated, map[string]interface{}{
+			"name":       t.Name,
+			"scope":      t.Scope,
+			"token":      token,
+			"created_at": t.CreatedAt,
+		})
+	}
+}
+
+func TokensDelete(d *database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name := c.Param("name")
+
+		err := d.UpdateSettings(func(s *database.Settings) error {
+			tokens := make([]*database.Token, 0, len(s.HttpTokens))
+			for _, t := range s.HttpTokens {
+				if t.Name != name {
+					tokens = append(tokens, t)
+				}
+			}
+			if len(tokens) == len(s.HttpTokens) {
+				return errTokenNotFound
+			}
+			s.HttpTokens = tokens
+			return nil
+		})
+		if errors.Is(err, errTokenNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"message": fmt.Sprintf("The token '%s' not found.", name),
+			})
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		middleware.AuditSummary(c, "token '%s' revoked", name)
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -11,7 +12,12 @@ import (
 	"github.com/ebadidev/arch-node/internal/database"
 	"github.com/ebadidev/arch-node/internal/http/handlers"
 	v1 "github.com/ebadidev/arch-node/internal/http/handlers/v1"
+	"github.com/ebadidev/arch-node/internal/utils"
+	"github.com/ebadidev/arch-node/pkg/audit"
+	"github.com/ebadidev/arch-node/pkg/certificate"
+	"github.com/ebadidev/arch-node/pkg/geodata"
 	"github.com/ebadidev/arch-node/pkg/http/middleware"
+	"github.com/ebadidev/arch-node/pkg/http/signature"
 	"github.com/ebadidev/arch-node/pkg/http/validator"
 	"github.com/ebadidev/arch-node/pkg/logger"
 	"github.com/ebadidev/arch-node/pkg/xray"
@@ -21,36 +27,131 @@ import (
 )
 
 type Server struct {
-	engine   *echo.Echo
-	config   *config.Config
-	xray     *xray.Xray
-	database *database.Database
-	l        *logger.Logger
+	engine      *echo.Echo
+	config      *config.Config
+	xray        *xray.Xray
+	geodata     *geodata.Manager
+	database    *database.Database
+	l           *logger.Logger
+	certificate *certificate.Reloader
+	bans        *middleware.Banlist
+	verifier    *signature.Verifier
+	audit       *audit.Log
+	shutdown    func()
 }
 
 // Run defines the required HTTP routes and starts the HTTP Server.
-func (s *Server) Run() {
+func (s *Server) Run() error {
 	s.engine.Use(echoMiddleware.CORS())
+	s.engine.Use(middleware.RequestId(s.l))
 	s.engine.Use(middleware.Logger(s.l))
 	s.engine.Use(middleware.General())
 
 	s.engine.GET("/", handlers.HomeShow())
 
 	g2 := s.engine.Group("/v1")
-	g2.Use(middleware.Authorize(func() string {
-		return s.database.Data.Settings.HttpToken
-	}))
+	g2.Use(middleware.Allowlist(s.database.AllowedIps))
+	g2.Use(middleware.Audit(s.audit, s.l))
+	g2.Use(middleware.Authorize(s.database.ResolveToken, s.bans, s.verifier))
 
-	g2.GET("/stats", v1.StatsShow(s.xray))
-	g2.POST("/configs", v1.ConfigsStore(s.xray))
-	g2.POST("/manager", v1.ManagerStore(s.database))
+	stats := middleware.Scope(middleware.ScopeStats)
+	users := middleware.Scope(middleware.ScopeUsers)
+	admin := middleware.Scope(middleware.ScopeAdmin)
+
+	g2.GET("/stats", v1.StatsShow(s.xray), stats)
+	g2.GET("/system", v1.SystemShow(s.database, s.xray), stats)
+	g2.GET("/outbounds/health", v1.OutboundsHealth(s.xray), stats)
+	g2.GET("/ports", v1.PortsIndex(s.xray), users)
+	g2.POST("/ports/check", v1.PortsCheck(s.xray), users)
+	g2.POST("/configs", v1.ConfigsStore(s.xray, s.geodata), users, middleware.RateLimit(1, 5))
+	g2.POST("/probe", v1.ProbeStore(s.xray), admin, middleware.RateLimit(1, 3))
+	g2.GET("/bans", v1.BansIndex(s.bans), admin)
+	g2.GET("/audit", v1.AuditIndex(s.audit), admin)
+	g2.GET("/backup", v1.BackupShow(s.config), admin)
+	g2.GET("/logs/stream", v1.LogsStream(s.l, s.xray), admin)
+	g2.POST("/xray/restart", v1.XrayRestart(s.xray), admin)
+	g2.GET("/geodata", v1.GeodataIndex(s.geodata), stats)
+	g2.POST("/geodata/:name", v1.GeodataStore(s.xray, s.geodata), admin, middleware.RateLimit(1, 3))
+	g2.POST("/geodata/:name/download", v1.GeodataDownload(s.config, s.xray, s.geodata), admin, middleware.RateLimit(1, 3))
+	g2.POST("/node/reload", v1.NodeReload(s.config, s.l, s.xray), admin)
+	g2.POST("/node/stop", v1.NodeStop(s.xray, s.shutdown), admin)
+	g2.POST("/manager", v1.ManagerStore(s.database), admin)
+	g2.POST("/settings/client-ca", v1.SettingsClientCaStore(s.database), admin)
+	g2.GET("/settings/allowlist", v1.SettingsAllowlistShow(s.database), admin)
+	g2.POST("/settings/allowlist", v1.SettingsAllowlistStore(s.database), admin)
+	g2.POST("/settings/signing", v1.SettingsSigningStore(s.database), admin)
+	g2.POST("/settings/public-ips", v1.SettingsPublicIpsStore(s.database), admin)
+	g2.POST("/settings/token/rotate", v1.TokenRotate(s.database), admin)
+	g2.GET("/settings/tokens", v1.TokensIndex(s.database), admin)
+	g2.POST("/settings/tokens", v1.TokensStore(s.database), admin)
+	g2.DELETE("/settings/tokens/:name", v1.TokensDelete(s.database), admin)
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
 	go func() {
-		address := fmt.Sprintf("%s:%d", "0.0.0.0", s.database.Data.Settings.HttpPort)
-		if err := s.engine.Start(address); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		address := fmt.Sprintf("%s:%d", "0.0.0.0", s.database.Settings().HttpPort)
+		var err error
+		if tlsConfig == nil {
+			err = s.engine.Start(address)
+		} else {
+			err = s.engine.StartServer(&http.Server{Addr: address, TLSConfig: tlsConfig})
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			s.l.Fatal("http server: cannot start", zap.String("address", address), zap.Error(err))
 		}
 	}()
+
+	return nil
+}
+
+// tlsConfig prepares the TLS configuration of the HTTP Server, it returns nil when TLS is off.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	certFile, keyFile := s.config.HttpTlsFiles()
+	if certFile == "" {
+		if s.database.Settings().HttpClientCa != "" {
+			return nil, errors.New("http server: manager CA is stored but TLS is off, enable TLS or remove the CA")
+		}
+		return nil, nil
+	}
+
+	if s.config.HttpServer.Tls.Mode == "self-signed" && !utils.FileExist(certFile) {
+		s.l.Info("http server: generating self-signed certificate...")
+		hosts := []string{"localhost", "127.0.0.1"}
+		if err := certificate.GenerateSelfSigned(certFile, keyFile, config.AppName, hosts, 10*365*24*time.Hour); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	s.certificate = certificate.NewReloader(s.l, certFile, keyFile)
+	if err := s.certificate.Init(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s.l.Info("http server: tls enabled", zap.String("fingerprint", s.certificate.Fingerprint()))
+
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetCertificate:     s.certificate.GetCertificate,
+		GetConfigForClient: s.tlsConfigForClient,
+	}, nil
+}
+
+// tlsConfigForClient enables mutual TLS when a manager CA is stored in the database, the CA is parsed when it is stored
+// and an invalid one is never stored. Without a CA, client certificates are requested but not verified, so they can be
+// checked before storing a new CA.
+func (s *Server) tlsConfigForClient(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+	c := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: s.certificate.GetCertificate,
+		ClientAuth:     tls.RequestClientCert,
+	}
+	if pool := s.database.ClientCaPool(); pool != nil {
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		c.ClientCAs = pool
+	}
+	return c, nil
 }
 
 // Close closes the HTTP Server.
@@ -67,10 +168,21 @@ func (s *Server) Close() error {
 }
 
 // New creates a new instance of HTTP Server.
-func New(config *config.Config, l *logger.Logger, x *xray.Xray, d *database.Database) *Server {
+func New(
+	c *config.Config, l *logger.Logger, x *xray.Xray, g *geodata.Manager, d *database.Database, a *audit.Log, shutdown func(),
+) *Server {
 	e := echo.New()
 	e.HideBanner = true
 	e.Validator = validator.New()
+	e.IPExtractor = middleware.IPExtractor(d.TrustedProxies)
 
-	return &Server{engine: e, config: config, l: l, xray: x, database: d}
+	bans := middleware.NewBanlist(config.HttpAuthMaxFailures, config.HttpAuthFailureWindow, config.HttpAuthBanDuration)
+
+	verifier := signature.NewVerifier(func() string {
+		return d.Settings().HttpSigningSecret
+	}, config.HttpSignatureMaxSkew, config.HttpSignatureNonceCapacity)
+
+	return &Server{
+		engine: e, config: c, l: l, xray: x, geodata: g, database: d, bans: bans, verifier: verifier, audit: a, shutdown: shutdown,
+	}
 }
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ebadidev/arch-node/internal/config"
+	"github.com/ebadidev/arch-node/internal/database"
+	"github.com/ebadidev/arch-node/pkg/certificate"
+	"github.com/ebadidev/arch-node/pkg/logger"
+)
+
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Chdir(t.TempDir())
+	for _, dir := range []string{"storage/logs", filepath.Dir(database.Path)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := logger.New("error", "2006-01-02 15:04:05", make(chan struct{}, 10))
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestTlsConfigClientCa(t *testing.T) {
+	l := newTestLogger(t)
+	if err := certificate.GenerateSelfSigned("ca.crt", "ca.key", "manager", nil, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	ca, err := os.ReadFile("ca.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := database.New(l)
+	err = d.UpdateSettings(func(s *database.Settings) error {
+		s.HttpClientCa = string(ca)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: config.New(), database: d, l: l}
+
+	s.config.HttpServer.Tls.Mode = "off"
+	if _, err = s.tlsConfig(); err == nil {
+		t.Error("Expected the server not to start without TLS while a manager CA is stored")
+	}
+
+	if err = certificate.GenerateSelfSigned("http.crt", "http.key", "node", []string{"localhost"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	s.config.HttpServer.Tls.Mode = "file"
+	s.config.HttpServer.Tls.CertFile, s.config.HttpServer.Tls.KeyFile = "http.crt", "http.key"
+	if _, err = s.tlsConfig(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := s.tlsConfigForClient(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.ClientAuth != tls.RequireAndVerifyClientCert || c.ClientCAs == nil {
+		t.Errorf("Expected client certificates to be verified with the stored CA, got %v", c.ClientAuth)
+	}
+}
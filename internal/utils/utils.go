@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"fmt"
 	"net"
 	"os"
@@ -45,3 +47,40 @@ func PortFree(port int) bool {
 
 	return true
 }
+
+// LocalAddress checks if the given IP address is assigned to a network interface of this server.
+func LocalAddress(ip net.IP) (bool, error) {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return false, err
+	}
+	for _, address := range addresses {
+		if network, ok := address.(*net.IPNet); ok && network.IP.Equal(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LocalNetwork checks if the given network falls within the network of an address assigned to a network interface of this server.
+func LocalNetwork(network *net.IPNet) (bool, error) {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return false, err
+	}
+	size, _ := network.Mask.Size()
+	for _, address := range addresses {
+		if local, ok := address.(*net.IPNet); ok && local.Contains(network.IP) {
+			if localSize, _ := local.Mask.Size(); localSize <= size {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// SecureEqual compares the given secrets in constant time, hashing them first so their lengths do not leak either.
+func SecureEqual(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Entry is a single record of the audit log.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	CallerIp  string    `json:"caller_ip,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	RequestId string    `json:"request_id,omitempty"`
+	Summary   string    `json:"summary"`
+	Status    int       `json:"status,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Log is an append-only audit log stored as JSON lines.
+type Log struct {
+	path   string
+	locker *sync.Mutex
+}
+
+// Record appends the given entry to the log.
+func (a *Log) Record(e *Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.locker.Lock()
+	defer a.locker.Unlock()
+
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	_, err = file.Write(append(line, '\n'))
+	return errors.WithStack(err)
+}
+
+// Query returns the last entries (at most limit) recorded between from and to, zero times are unbounded.
+func (a *Log) Query(from, to time.Time, limit int) ([]*Entry, error) {
+	a.locker.Lock()
+	defer a.locker.Unlock()
+
+	entries := make([]*Entry, 0)
+
+	file, err := os.Open(a.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if (!from.IsZero() && e.Time.Before(from)) || (!to.IsZero() && e.Time.After(to)) {
+			continue
+		}
+		entries = append(entries, &e)
+		if limit > 0 && len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+
+	return entries, errors.WithStack(scanner.Err())
+}
+
+func New(path string) *Log {
+	return &Log{path: path, locker: &sync.Mutex{}}
+}
@@ -0,0 +1,351 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Version is the archive format version, archives of a newer version are rejected.
+const Version = 1
+
+const manifestName = "manifest.json"
+
+// encryptedMagic prefixes encrypted archives, it is followed by the salt, the nonce and the AES-GCM sealed archive.
+const encryptedMagic = "ARCHBAK1"
+
+const (
+	saltSize   = 16
+	keySize    = 32
+	iterations = 600000
+)
+
+type Manifest struct {
+	Version    int     `json:"version"`
+	AppVersion string  `json:"app_version"`
+	CreatedAt  int64   `json:"created_at"`
+	Files      []*File `json:"files"`
+}
+
+type File struct {
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// Source is a file to archive, under its relative name in the archive.
+type Source struct {
+	Name string
+	Path string
+}
+
+// Sources returns the sources of the given relative paths, archived under their own paths.
+func Sources(paths ...string) []*Source {
+	sources := make([]*Source, 0, len(paths))
+	for _, p := range paths {
+		sources = append(sources, &Source{Name: p, Path: p})
+	}
+	return sources
+}
+
+// Archive is a backup read into memory, keyed by the relative file paths.
+type Archive struct {
+	Manifest *Manifest
+	Files    map[string][]byte
+}
+
+// Write archives the given files as a gzipped tar with a manifest, encrypting it when a passphrase is given.
+// Missing files are skipped.
+func Write(w io.Writer, sources []*Source, appVersion, passphrase string) error {
+	manifest := &Manifest{Version: Version, AppVersion: appVersion, CreatedAt: time.Now().Unix(), Files: []*File{}}
+	contents := map[string][]byte{}
+	for _, s := range sources {
+		if !safePath(s.Name) {
+			return errors.Errorf("unsafe name %s", s.Name)
+		}
+		content, err := os.ReadFile(s.Path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return errors.WithStack(err)
+		}
+		sum := sha256.Sum256(content)
+		manifest.Files = append(manifest.Files, &File{Path: s.Name, Size: len(content), Sha256: hex.EncodeToString(sum[:])})
+		contents[s.Name] = content
+	}
+
+	var buffer bytes.Buffer
+	gw := gzip.NewWriter(&buffer)
+	tw := tar.NewWriter(gw)
+
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err = writeEntry(tw, manifestName, manifestContent); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, f := range manifest.Files {
+		if err = writeEntry(tw, f.Path, contents[f.Path]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = gw.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	content := buffer.Bytes()
+	if passphrase != "" {
+		if content, err = encrypt(content, passphrase); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	_, err = w.Write(content)
+	return errors.WithStack(err)
+}
+
+func writeEntry(tw *tar.Writer, name string, content []byte) error {
+	header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return errors.WithStack(err)
+	}
+	_, err := tw.Write(content)
+	return errors.WithStack(err)
+}
+
+// Read loads and verifies an archive: its format version, the listed files and their checksums.
+func Read(r io.Reader, passphrase string) (*Archive, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if bytes.HasPrefix(content, []byte(encryptedMagic)) {
+		if passphrase == "" {
+			return nil, errors.New("the backup is encrypted, a passphrase is required")
+		}
+		if content, err = decrypt(content, passphrase); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid backup")
+	}
+	tr := tar.NewReader(gr)
+
+	a := &Archive{Files: map[string][]byte{}}
+	var manifestContent []byte
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid backup")
+		}
+		entry, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if header.Name == manifestName {
+			manifestContent = entry
+			continue
+		}
+		if !safePath(header.Name) {
+			return nil, errors.Errorf("unsafe path %s in backup", header.Name)
+		}
+		a.Files[header.Name] = entry
+	}
+
+	if manifestContent == nil {
+		return nil, errors.New("no manifest in backup")
+	}
+	if err = json.Unmarshal(manifestContent, &a.Manifest); err != nil {
+		return nil, errors.Wrap(err, "invalid manifest")
+	}
+	if a.Manifest.Version < 1 || a.Manifest.Version > Version {
+		return nil, errors.Errorf("unsupported backup version %d", a.Manifest.Version)
+	}
+
+	if len(a.Manifest.Files) != len(a.Files) {
+		return nil, errors.New("the manifest does not match the backup files")
+	}
+	for _, f := range a.Manifest.Files {
+		entry, found := a.Files[f.Path]
+		if !found {
+			return nil, errors.Errorf("file %s is missing from backup", f.Path)
+		}
+		sum := sha256.Sum256(entry)
+		if hex.EncodeToString(sum[:]) != f.Sha256 {
+			return nil, errors.Errorf("checksum mismatch for %s", f.Path)
+		}
+	}
+
+	return a, nil
+}
+
+// safePath reports whether the entry path is relative and stays inside the working directory.
+func safePath(p string) bool {
+	return p != "" && !path.IsAbs(p) && path.Clean(p) == p && p != ".." && !strings.HasPrefix(p, "../")
+}
+
+// Validate runs the validator of each file, a nil validator accepts any content.
+// Files without an entry in validators are rejected, so an archive cannot write outside the expected paths.
+func (a *Archive) Validate(validators map[string]func([]byte) error) error {
+	for _, f := range a.Manifest.Files {
+		v, found := validators[f.Path]
+		if !found {
+			return errors.Errorf("unexpected file %s in backup", f.Path)
+		}
+		if v == nil {
+			continue
+		}
+		if err := v(a.Files[f.Path]); err != nil {
+			return errors.Wrapf(err, "invalid %s", f.Path)
+		}
+	}
+	return nil
+}
+
+// Restore replaces the files with the archived ones, each at its archived path unless the destinations map it to another path.
+// All of them are staged next to the originals first, then swapped in
+// while the originals are kept as ".bak" copies. When a step fails, the replaced files are put back and the staged ones removed,
+// so the files are either all restored or left as they were.
+func (a *Archive) Restore(destinations map[string]string) error {
+	destination := func(f *File) string {
+		if p, found := destinations[f.Path]; found {
+			return p
+		}
+		return f.Path
+	}
+
+	var staged []string
+	defer func() {
+		for _, temp := range staged {
+			_ = os.Remove(temp)
+		}
+	}()
+	for _, f := range a.Manifest.Files {
+		if err := os.MkdirAll(filepath.Dir(destination(f)), 0755); err != nil {
+			return errors.WithStack(err)
+		}
+		temp := destination(f) + ".restore"
+		staged = append(staged, temp)
+		if err := os.WriteFile(temp, a.Files[f.Path], 0600); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// swapped lists the restored files and whether an original was moved to its ".bak" copy.
+	type swap struct {
+		path     string
+		original bool
+	}
+	var swapped []swap
+	rollback := func() {
+		for i := len(swapped) - 1; i >= 0; i-- {
+			if swapped[i].original {
+				_ = os.Rename(swapped[i].path+".bak", swapped[i].path)
+			} else {
+				_ = os.Remove(swapped[i].path)
+			}
+		}
+	}
+	for _, f := range a.Manifest.Files {
+		p := destination(f)
+		s := swap{path: p}
+		if _, err := os.Lstat(p); err == nil {
+			if err = os.Rename(p, p+".bak"); err != nil {
+				rollback()
+				return errors.WithStack(err)
+			}
+			s.original = true
+		}
+		swapped = append(swapped, s)
+		if err := os.Rename(p+".restore", p); err != nil {
+			rollback()
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, s := range swapped {
+		if s.original {
+			_ = os.Remove(s.path + ".bak")
+		}
+	}
+	return nil
+}
+
+func newGcm(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	return gcm, errors.WithStack(err)
+}
+
+func encrypt(content []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	gcm, err := newGcm(passphrase, salt)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	header := append(append([]byte(encryptedMagic), salt...), nonce...)
+	return gcm.Seal(header, nonce, content, []byte(encryptedMagic)), nil
+}
+
+func decrypt(content []byte, passphrase string) ([]byte, error) {
+	content = content[len(encryptedMagic):]
+	if len(content) < saltSize {
+		return nil, errors.New("invalid encrypted backup")
+	}
+	gcm, err := newGcm(passphrase, content[:saltSize])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	content = content[saltSize:]
+	if len(content) < gcm.NonceSize() {
+		return nil, errors.New("invalid encrypted backup")
+	}
+
+	plain, err := gcm.Open(nil, content[:gcm.NonceSize()], content[gcm.NonceSize():], []byte(encryptedMagic))
+	if err != nil {
+		return nil, errors.New("cannot decrypt the backup, wrong passphrase or corrupted file")
+	}
+	return plain, nil
+}
@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T) []*Source {
+	t.Chdir(t.TempDir())
+	files := map[string]string{"storage/database/app.json": `{"settings":{}}`, "storage/app/xray.json": `{}`}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Sources("storage/database/app.json", "storage/app/xray.json", "storage/app/missing.crt")
+}
+
+func TestRoundTrip(t *testing.T) {
+	paths := writeTestFiles(t)
+
+	for _, passphrase := range []string{"", "secret"} {
+		var buffer bytes.Buffer
+		if err := Write(&buffer, paths, "v1", passphrase); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		encrypted := bytes.HasPrefix(buffer.Bytes(), []byte(encryptedMagic))
+		if encrypted != (passphrase != "") {
+			t.Errorf("Expected encrypted=%v for passphrase %q", passphrase != "", passphrase)
+		}
+
+		a, err := Read(bytes.NewReader(buffer.Bytes()), passphrase)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(a.Manifest.Files) != 2 {
+			t.Fatalf("Expected 2 files, got %d", len(a.Manifest.Files))
+		}
+		if string(a.Files["storage/app/xray.json"]) != `{}` {
+			t.Errorf("Unexpected content %s", a.Files["storage/app/xray.json"])
+		}
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	paths := writeTestFiles(t)
+
+	t.Run("Wrong Passphrase", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := Write(&buffer, paths, "v1", "secret"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Read(bytes.NewReader(buffer.Bytes()), "wrong"); err == nil {
+			t.Error("Expected error for wrong passphrase")
+		}
+		if _, err := Read(bytes.NewReader(buffer.Bytes()), ""); err == nil {
+			t.Error("Expected error for missing passphrase")
+		}
+	})
+
+	t.Run("Tampered Content", func(t *testing.T) {
+		var buffer bytes.Buffer
+		gw := gzip.NewWriter(&buffer)
+		tw := tar.NewWriter(gw)
+		manifest := `{"version":1,"files":[{"path":"a.json","size":2,"sha256":"00"}]}`
+		_ = writeEntry(tw, manifestName, []byte(manifest))
+		_ = writeEntry(tw, "a.json", []byte("{}"))
+		_ = tw.Close()
+		_ = gw.Close()
+		if _, err := Read(&buffer, ""); err == nil {
+			t.Error("Expected error for checksum mismatch")
+		}
+	})
+
+	t.Run("Unsafe Path", func(t *testing.T) {
+		for _, p := range []string{"../etc/passwd", "/etc/passwd", "a/../../b", ""} {
+			if safePath(p) {
+				t.Errorf("Expected %q to be unsafe", p)
+			}
+		}
+	})
+
+	t.Run("Unexpected File", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := Write(&buffer, paths, "v1", ""); err != nil {
+			t.Fatal(err)
+		}
+		a, err := Read(&buffer, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = a.Validate(map[string]func([]byte) error{"storage/database/app.json": nil}); err == nil {
+			t.Error("Expected error for a file without validator")
+		}
+	})
+}
+
+func TestRestore(t *testing.T) {
+	paths := writeTestFiles(t)
+	var buffer bytes.Buffer
+	if err := Write(&buffer, paths, "v1", ""); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Read(&buffer, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Files["storage/database/app.json"] = []byte(`{"settings":{"http_port":8080}}`)
+	a.Files["storage/app/xray.json"] = []byte(`{"log":{}}`)
+
+	t.Run("Rollback", func(t *testing.T) {
+		// A directory in the way of the second .bak copy fails the swap after the first file is replaced.
+		blocker := a.Manifest.Files[1].Path + ".bak"
+		if err := os.MkdirAll(filepath.Join(blocker, "blocker"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			_ = os.RemoveAll(blocker)
+		}()
+
+		if err := a.Restore(nil); err == nil {
+			t.Fatal("Expected error for a failed swap")
+		}
+		for name, content := range map[string]string{"storage/database/app.json": `{"settings":{}}`, "storage/app/xray.json": `{}`} {
+			current, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(current) != content {
+				t.Errorf("Expected %s to be rolled back to %s, got %s", name, content, current)
+			}
+			if _, err = os.Stat(name + ".restore"); !os.IsNotExist(err) {
+				t.Errorf("Expected the staged %s to be removed", name)
+			}
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		if err := a.Restore(nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, f := range a.Manifest.Files {
+			current, err := os.ReadFile(f.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(current) != string(a.Files[f.Path]) {
+				t.Errorf("Expected %s to be restored, got %s", f.Path, current)
+			}
+			for _, suffix := range []string{".restore", ".bak"} {
+				if _, err = os.Stat(f.Path + suffix); !os.IsNotExist(err) {
+					t.Errorf("Expected %s%s to be removed", f.Path, suffix)
+				}
+			}
+		}
+	})
+}
+
+func TestRestoreDestinations(t *testing.T) {
+	sources := writeTestFiles(t)
+	if err := os.WriteFile("outside.pem", []byte("certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sources = append(sources, &Source{Name: "tls/file.crt", Path: "outside.pem"})
+
+	var buffer bytes.Buffer
+	if err := Write(&buffer, sources, "v1", ""); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Read(&buffer, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a.Files["tls/file.crt"]) != "certificate" {
+		t.Fatalf("Expected the source to be archived under its name, got %v", a.Manifest.Files)
+	}
+
+	destination := filepath.Join(t.TempDir(), "etc", "node.crt")
+	if err = a.Restore(map[string]string{"tls/file.crt": destination}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if content, err := os.ReadFile(destination); err != nil || string(content) != "certificate" {
+		t.Errorf("Expected the file restored to its destination, got %s (%v)", content, err)
+	}
+	if _, err = os.Stat("tls/file.crt"); !os.IsNotExist(err) {
+		t.Error("Expected the file not to be restored at its archived name")
+	}
+
+	if err = Write(&buffer, []*Source{{Name: "../outside.pem", Path: "outside.pem"}}, "v1", ""); err == nil {
+		t.Error("Expected error for an unsafe name")
+	}
+}
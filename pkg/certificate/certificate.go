@@ -0,0 +1,135 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// GenerateSelfSigned creates a new ECDSA key pair and a self-signed certificate for the given hosts,
+// and writes them to the given paths in PEM format.
+func GenerateSelfSigned(certPath, keyPath, commonName string, hosts []string, validity time.Duration) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	return errors.WithStack(err)
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the first certificate in the given PEM file.
+func Fingerprint(certPath string) (string, error) {
+	content, err := os.ReadFile(certPath)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", errors.Errorf("no certificate found in %s", certPath)
+	}
+
+	return FingerprintOf(block.Bytes), nil
+}
+
+// FingerprintOf returns the SHA-256 fingerprint of the given DER-encoded certificate,
+// formatted as colon-separated uppercase hex pairs.
+func FingerprintOf(der []byte) string {
+	sum := sha256.Sum256(der)
+	pairs := make([]string, len(sum))
+	for i, b := range sum {
+		pairs[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(pairs, ":")
+}
+
+// Load reads the key pair from the given paths.
+func Load(certPath, keyPath string) (*tls.Certificate, error) {
+	c, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &c, nil
+}
+
+// Pool parses the given PEM-encoded certificates into a certificate pool.
+// Every block must be a valid certificate, so a partly broken bundle is rejected rather than partly trusted.
+func Pool(content string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	count := 0
+	rest := []byte(content)
+	for len(strings.TrimSpace(string(rest))) > 0 {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return nil, errors.New("invalid PEM data")
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, errors.Errorf("unexpected PEM block %s", block.Type)
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid certificate")
+		}
+		pool.AddCert(c)
+		count++
+	}
+	if count == 0 {
+		return nil, errors.New("no valid certificate found")
+	}
+	return pool, nil
+}
+
+// Identity returns a short description of the given certificate for logging.
+func Identity(c *x509.Certificate) string {
+	return fmt.Sprintf("%s (%s)", c.Subject.CommonName, FingerprintOf(c.Raw))
+}
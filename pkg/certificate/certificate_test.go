@@ -0,0 +1,40 @@
+package certificate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	root := t.TempDir()
+	certPath, keyPath := filepath.Join(root, "ca.crt"), filepath.Join(root, "ca.key")
+	if err := GenerateSelfSigned(certPath, keyPath, "Manager CA", []string{"localhost"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := os.ReadFile(certPath)
+	key, _ := os.ReadFile(keyPath)
+
+	for name, content := range map[string]string{
+		"single": string(ca),
+		"bundle": string(ca) + "\n" + string(ca),
+	} {
+		if _, err := Pool(content); err != nil {
+			t.Errorf("Unexpected error for %s: %v", name, err)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"empty":          "",
+		"not pem":        "not a certificate",
+		"key":            string(key),
+		"trailing junk":  string(ca) + "junk",
+		"broken cert":    "-----BEGIN CERTIFICATE-----\nYWJj\n-----END CERTIFICATE-----\n",
+		"cert and a key": string(ca) + string(key),
+	} {
+		if _, err := Pool(content); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
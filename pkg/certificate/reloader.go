@@ -0,0 +1,99 @@
+package certificate
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Reloader serves a key pair from disk and reloads it whenever the files change, without a restart.
+type Reloader struct {
+	l           *logger.Logger
+	certPath    string
+	keyPath     string
+	locker      *sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+func (r *Reloader) Init() error {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+
+	return errors.WithStack(r.load())
+}
+
+func (r *Reloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c, err := Load(r.certPath, r.keyPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	r.certificate = c
+	r.modTime = modTime
+	return nil
+}
+
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certPath, r.keyPath} {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return latest, errors.WithStack(err)
+		}
+		if stat.ModTime().After(latest) {
+			latest = stat.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+// The current certificate is kept when the files on disk are missing or invalid.
+func (r *Reloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+
+	if modTime, err := r.latestModTime(); err == nil && !modTime.Equal(r.modTime) {
+		if err = r.load(); err != nil {
+			r.l.Error("certificate: cannot reload", zap.String("path", r.certPath), zap.Error(err))
+		} else {
+			r.l.Info("certificate: reloaded", zap.String("path", r.certPath))
+		}
+	}
+
+	if r.certificate == nil {
+		return nil, errors.New("certificate: not loaded")
+	}
+	return r.certificate, nil
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate.
+func (r *Reloader) GetClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.GetCertificate(nil)
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the currently served certificate.
+func (r *Reloader) Fingerprint() string {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+
+	if r.certificate == nil || len(r.certificate.Certificate) == 0 {
+		return ""
+	}
+	return FingerprintOf(r.certificate.Certificate[0])
+}
+
+func NewReloader(l *logger.Logger, certPath, keyPath string) *Reloader {
+	return &Reloader{l: l, certPath: certPath, keyPath: keyPath, locker: &sync.Mutex{}}
+}
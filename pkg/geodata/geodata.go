@@ -0,0 +1,415 @@
+package geodata
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	GeoIp   = "geoip.dat"
+	GeoSite = "geosite.dat"
+)
+
+// Names lists the managed data files.
+var Names = []string{GeoIp, GeoSite}
+
+// MaxSize is the largest accepted data file, in bytes.
+const MaxSize = 64 << 20
+
+const manifestName = "geodata.json"
+
+// File is an installed data file, Version is empty when the file was not installed by the manager (e.g. bundled with Xray).
+type File struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Sha256    string `json:"sha256"`
+	Size      int64  `json:"size"`
+	UpdatedAt int64  `json:"updated_at"`
+	Codes     int    `json:"codes"`
+}
+
+type record struct {
+	Version   string `json:"version"`
+	Sha256    string `json:"sha256"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type cachedCodes struct {
+	sha256 string
+	codes  map[string]bool
+}
+
+// Manager keeps the geoip.dat and geosite.dat files of a directory (next to the Xray binary) and their versions.
+type Manager struct {
+	directory string
+	client    *http.Client
+	locker    *sync.Mutex
+	cache     map[string]*cachedCodes
+}
+
+// Upload is a verified data file written next to its destination and waiting to be installed or discarded.
+type Upload struct {
+	m      *Manager
+	Name   string
+	path   string
+	Sha256 string
+	Size   int64
+	Codes  map[string]bool
+}
+
+func New(directory string, client *http.Client) *Manager {
+	return &Manager{directory: directory, client: client, locker: &sync.Mutex{}, cache: map[string]*cachedCodes{}}
+}
+
+// Valid reports whether the name is a managed data file.
+func Valid(name string) bool {
+	return slices.Contains(Names, name)
+}
+
+// List returns the installed data files, missing files are skipped.
+func (m *Manager) List() ([]*File, error) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+
+	records, err := m.records()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	files := make([]*File, 0, len(Names))
+	for _, name := range Names {
+		sum, codes, err := m.codes(name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, errors.WithStack(err)
+		}
+		stat, err := os.Stat(filepath.Join(m.directory, name))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		f := &File{Name: name, Sha256: sum, Size: stat.Size(), UpdatedAt: stat.ModTime().Unix(), Codes: len(codes)}
+		if r, found := records[name]; found && r.Sha256 == sum {
+			f.Version, f.UpdatedAt = r.Version, r.UpdatedAt
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Codes returns the upper-cased country codes (geoip.dat) or categories (geosite.dat) of the installed file.
+func (m *Manager) Codes(name string) (map[string]bool, error) {
+	m.locker.Lock()
+	defer m.locker.Unlock()
+
+	_, codes, err := m.codes(name)
+	return codes, errors.WithStack(err)
+}
+
+// Check returns an error naming the geosite categories and geoip codes absent from the installed files.
+func (m *Manager) Check(sites, ips []string) error {
+	for _, name := range Names {
+		references := sites
+		if name == GeoIp {
+			references = ips
+		}
+		if len(references) == 0 {
+			continue
+		}
+		codes, err := m.Codes(name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.WithStack(err)
+		}
+		if absent := missing(codes, references); len(absent) > 0 {
+			return errors.Errorf("%s has no %s", name, strings.Join(absent, ", "))
+		}
+	}
+	return nil
+}
+
+// Check returns an error naming the geosite categories (for geosite.dat) or geoip codes (for geoip.dat) absent from the upload.
+func (u *Upload) Check(sites, ips []string) error {
+	references := sites
+	if u.Name == GeoIp {
+		references = ips
+	}
+	if absent := missing(u.Codes, references); len(absent) > 0 {
+		return errors.Errorf("%s has no %s", u.Name, strings.Join(absent, ", "))
+	}
+	return nil
+}
+
+// missing returns the references (case-insensitive) absent from the codes.
+func missing(codes map[string]bool, references []string) []string {
+	var absent []string
+	for _, r := range references {
+		if !codes[strings.ToUpper(r)] && !slices.Contains(absent, r) {
+			absent = append(absent, r)
+		}
+	}
+	return absent
+}
+
+func (m *Manager) codes(name string) (string, map[string]bool, error) {
+	content, err := os.ReadFile(filepath.Join(m.directory, name))
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	sum := checksum(content)
+	if c, found := m.cache[name]; found && c.sha256 == sum {
+		return sum, c.codes, nil
+	}
+	codes, err := Parse(content)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "invalid %s", name)
+	}
+	m.cache[name] = &cachedCodes{sha256: sum, codes: codes}
+	return sum, codes, nil
+}
+
+// Parse returns the upper-cased codes of a GeoIPList or GeoSiteList, both are protobuf lists (field 1)
+// of entries whose country_code is their field 1.
+func Parse(content []byte) (map[string]bool, error) {
+	codes := map[string]bool{}
+	for len(content) > 0 {
+		number, kind, n := protowire.ConsumeTag(content)
+		if n < 0 {
+			return nil, errors.WithStack(protowire.ParseError(n))
+		}
+		content = content[n:]
+		if number != 1 || kind != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(number, kind, content); n < 0 {
+				return nil, errors.WithStack(protowire.ParseError(n))
+			}
+			content = content[n:]
+			continue
+		}
+		entry, n := protowire.ConsumeBytes(content)
+		if n < 0 {
+			return nil, errors.WithStack(protowire.ParseError(n))
+		}
+		content = content[n:]
+
+		code, err := entryCode(entry)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		codes[strings.ToUpper(code)] = true
+	}
+	if len(codes) == 0 {
+		return nil, errors.New("no entries found")
+	}
+	return codes, nil
+}
+
+func entryCode(entry []byte) (string, error) {
+	for len(entry) > 0 {
+		number, kind, n := protowire.ConsumeTag(entry)
+		if n < 0 {
+			return "", errors.WithStack(protowire.ParseError(n))
+		}
+		entry = entry[n:]
+		if number == 1 && kind == protowire.BytesType {
+			code, n := protowire.ConsumeString(entry)
+			if n < 0 {
+				return "", errors.WithStack(protowire.ParseError(n))
+			}
+			return code, nil
+		}
+		if n = protowire.ConsumeFieldValue(number, kind, entry); n < 0 {
+			return "", errors.WithStack(protowire.ParseError(n))
+		}
+		entry = entry[n:]
+	}
+	return "", errors.New("entry without code")
+}
+
+// Stage writes the content to a temporary file next to the destination and verifies it parses,
+// and matches the expected SHA-256 checksum when one is given.
+func (m *Manager) Stage(name string, r io.Reader, expectedSha256 string) (*Upload, error) {
+	if !Valid(name) {
+		return nil, errors.Errorf("unknown data file %s", name)
+	}
+	content, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(content) > MaxSize {
+		return nil, errors.Errorf("%s is larger than %d bytes", name, MaxSize)
+	}
+
+	u := &Upload{m: m, Name: name, Sha256: checksum(content), Size: int64(len(content))}
+	if expectedSha256 != "" && !strings.EqualFold(expectedSha256, u.Sha256) {
+		return nil, errors.Errorf("checksum mismatch for %s, expected %s, got %s", name, expectedSha256, u.Sha256)
+	}
+	if u.Codes, err = Parse(content); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", name)
+	}
+
+	if err = os.MkdirAll(m.directory, 0755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	file, err := os.CreateTemp(m.directory, name+".*.tmp")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	u.path = file.Name()
+	if err = file.Chmod(0644); err != nil {
+		_ = file.Close()
+		u.Discard()
+		return nil, errors.WithStack(err)
+	}
+	if _, err = file.Write(content); err != nil {
+		_ = file.Close()
+		u.Discard()
+		return nil, errors.WithStack(err)
+	}
+	if err = file.Close(); err != nil {
+		u.Discard()
+		return nil, errors.WithStack(err)
+	}
+	return u, nil
+}
+
+// Download fetches the data file from the mirror (base URL) and stages it.
+// The checksum is verified against "<name>.sha256sum" when the mirror provides it.
+// The version is the Last-Modified date of the file, or the current time.
+func (m *Manager) Download(ctx context.Context, mirror, name string) (*Upload, string, error) {
+	if !Valid(name) {
+		return nil, "", errors.Errorf("unknown data file %s", name)
+	}
+	url := strings.TrimSuffix(mirror, "/") + "/" + name
+
+	expected, err := m.remoteChecksum(ctx, url+".sha256sum")
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	response, err := m.get(ctx, url)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, "", errors.Errorf("cannot download %s, status %d", url, response.StatusCode)
+	}
+
+	version := time.Now().UTC().Format("200601021504")
+	if t, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		version = t.UTC().Format("200601021504")
+	}
+
+	u, err := m.Stage(name, response.Body, expected)
+	return u, version, errors.WithStack(err)
+}
+
+// remoteChecksum returns the checksum published next to the file, or an empty string when there is none.
+func (m *Manager) remoteChecksum(ctx context.Context, url string) (string, error) {
+	response, err := m.get(ctx, url)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", errors.Errorf("cannot download %s, status %d", url, response.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, 1024))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", errors.Errorf("empty checksum %s", url)
+	}
+	return fields[0], nil
+}
+
+func (m *Manager) get(ctx context.Context, url string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	response, err := m.client.Do(request)
+	return response, errors.WithStack(err)
+}
+
+// Install replaces the installed file with the upload (renaming it, so Xray never reads a partial file) and records its version.
+func (u *Upload) Install(version string) (*File, error) {
+	u.m.locker.Lock()
+	defer u.m.locker.Unlock()
+
+	records, err := u.m.records()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = os.Rename(u.path, filepath.Join(u.m.directory, u.Name)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	u.m.cache[u.Name] = &cachedCodes{sha256: u.Sha256, codes: u.Codes}
+
+	r := &record{Version: version, Sha256: u.Sha256, UpdatedAt: time.Now().Unix()}
+	records[u.Name] = r
+	if err = u.m.saveRecords(records); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &File{Name: u.Name, Version: r.Version, Sha256: r.Sha256, Size: u.Size, UpdatedAt: r.UpdatedAt, Codes: len(u.Codes)}, nil
+}
+
+// Discard removes the staged file, it is a no-op after Install.
+func (u *Upload) Discard() {
+	_ = os.Remove(u.path)
+}
+
+func (m *Manager) records() (map[string]*record, error) {
+	records := map[string]*record{}
+	content, err := os.ReadFile(filepath.Join(m.directory, manifestName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return records, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	if err = json.Unmarshal(content, &records); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", manifestName)
+	}
+	return records, nil
+}
+
+func (m *Manager) saveRecords(records map[string]*record) error {
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	path := filepath.Join(m.directory, manifestName)
+	if err = os.WriteFile(path+".tmp", content, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(path+".tmp", path))
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
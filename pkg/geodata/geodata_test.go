@@ -0,0 +1,164 @@
+package geodata
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// makeList encodes a GeoSiteList (or GeoIPList) whose entries have the given codes and a dummy second field.
+func makeList(codes ...string) []byte {
+	var list []byte
+	for _, code := range codes {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, []byte{0x08, 0x01})
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, code)
+		list = protowire.AppendTag(list, 1, protowire.BytesType)
+		list = protowire.AppendBytes(list, entry)
+	}
+	return list
+}
+
+func TestParse(t *testing.T) {
+	codes, err := Parse(makeList("CN", "category-ads-all"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(codes) != 2 || !codes["CN"] || !codes["CATEGORY-ADS-ALL"] {
+		t.Errorf("Unexpected codes %v", codes)
+	}
+
+	for _, content := range [][]byte{nil, []byte("not a geosite file"), makeList()} {
+		if _, err = Parse(content); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	directory := t.TempDir()
+	m := New(directory, http.DefaultClient)
+
+	if files, err := m.List(); err != nil || len(files) != 0 {
+		t.Fatalf("Expected no files, got %v %v", files, err)
+	}
+	if err := m.Check([]string{"ir"}, nil); err == nil {
+		t.Error("Expected error for a category without geosite.dat")
+	}
+
+	content := makeList("IR", "CATEGORY-ADS-ALL")
+	if _, err := m.Stage(GeoSite, bytes.NewReader(content), "0000"); err == nil {
+		t.Error("Expected checksum mismatch")
+	}
+	if _, err := m.Stage("other.dat", bytes.NewReader(content), ""); err == nil {
+		t.Error("Expected error for an unknown file")
+	}
+
+	u, err := m.Stage(GeoSite, bytes.NewReader(content), checksum(content))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err = u.Check([]string{"cn"}, nil); err == nil {
+		t.Error("Expected error for a missing category")
+	}
+	if err = u.Check([]string{"ir"}, []string{"cn"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	file, err := u.Install("202501010000")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	u.Discard()
+	if file.Version != "202501010000" || file.Codes != 2 || file.Sha256 != checksum(content) {
+		t.Errorf("Unexpected file %+v", file)
+	}
+
+	installed, err := os.ReadFile(filepath.Join(directory, GeoSite))
+	if err != nil || !bytes.Equal(installed, content) {
+		t.Errorf("Expected the file to be installed: %v", err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(directory, "*.tmp")); len(temps) != 0 {
+		t.Errorf("Unexpected temporary files %v", temps)
+	}
+
+	files, err := New(directory, http.DefaultClient).List()
+	if err != nil || len(files) != 1 || files[0].Version != "202501010000" {
+		t.Errorf("Expected the version to be listed, got %v %v", files, err)
+	}
+
+	if err = m.Check([]string{"ir", "category-ads-all"}, nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err = m.Check([]string{"cn"}, nil); err == nil {
+		t.Error("Expected error for a missing category")
+	}
+
+	if err = os.WriteFile(filepath.Join(directory, GeoSite), makeList("CN"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err = m.List()
+	if err != nil || len(files) != 1 || files[0].Version != "" {
+		t.Errorf("Expected no version for a replaced file, got %v %v", files, err)
+	}
+	if err = m.Check([]string{"cn"}, nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	content := makeList("CN", "IR")
+	modified := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+	sums := map[string]string{
+		"/" + GeoIp + ".sha256sum":   checksum(content) + "  geoip.dat\n",
+		"/" + GeoSite + ".sha256sum": "0000  geosite.dat\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256sum") {
+			if sum, found := sums[r.URL.Path]; found {
+				_, _ = w.Write([]byte(sum))
+			} else {
+				http.NotFound(w, r)
+			}
+			return
+		}
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	m := New(t.TempDir(), server.Client())
+
+	u, version, err := m.Download(context.Background(), server.URL+"/", GeoIp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer u.Discard()
+	if version != "202506011230" {
+		t.Errorf("Expected the version from Last-Modified, got %s", version)
+	}
+	if !u.Codes["IR"] {
+		t.Errorf("Unexpected codes %v", u.Codes)
+	}
+
+	if _, _, err = m.Download(context.Background(), server.URL, GeoSite); err == nil {
+		t.Error("Expected checksum mismatch")
+	}
+
+	delete(sums, "/"+GeoSite+".sha256sum")
+	u, _, err = m.Download(context.Background(), server.URL, GeoSite)
+	if err != nil {
+		t.Fatalf("Expected a file without published checksum to be accepted: %v", err)
+	}
+	u.Discard()
+}
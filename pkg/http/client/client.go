@@ -2,10 +2,12 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/pkg/http/signature"
 	"github.com/labstack/echo/v4"
 	"io"
 	"net/http"
@@ -16,9 +18,17 @@ type Client struct {
 	e          *http.Client
 	appName    string
 	appVersion string
+	secret     func() string
 }
 
-func (c *Client) Do(method, url, token string, body interface{}) ([]byte, error) {
+type requestIdKey struct{}
+
+// WithRequestId returns a context whose requests carry the given X-Request-Id header, to correlate them on the other side.
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+func (c *Client) Do(ctx context.Context, method, url, token string, body interface{}) ([]byte, error) {
 	info := map[string]interface{}{
 		"request_method": method,
 		"request_url":    url,
@@ -37,7 +47,7 @@ func (c *Client) Do(method, url, token string, body interface{}) ([]byte, error)
 		info["request_body"] = string(requestBody)
 	}
 
-	request, err := http.NewRequest(method, url, requestReader)
+	request, err := http.NewRequestWithContext(ctx, method, url, requestReader)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot create request, %v", info)
 	}
@@ -46,6 +56,16 @@ func (c *Client) Do(method, url, token string, body interface{}) ([]byte, error)
 	request.Header.Set(echo.HeaderAuthorization, fmt.Sprintf("Bearer %s", token))
 	request.Header.Set("X-App-Name", c.appName)
 	request.Header.Set("X-App-Version", c.appVersion)
+	if id, ok := ctx.Value(requestIdKey{}).(string); ok && id != "" {
+		request.Header.Set(echo.HeaderXRequestID, id)
+		info["request_id"] = id
+	}
+
+	if c.secret != nil && c.secret() != "" {
+		if err = signature.SignRequest(request, c.secret(), requestBody); err != nil {
+			return nil, errors.Wrapf(err, "cannot sign request, %v", info)
+		}
+	}
 
 	response, err := c.e.Do(request)
 	if err != nil {
@@ -74,16 +94,24 @@ func (c *Client) Do(method, url, token string, body interface{}) ([]byte, error)
 	return nil, errors.Errorf("unknown respose received, %s", info)
 }
 
-func (c *Client) DoThrough(proxy, method, url, token string, body interface{}) ([]byte, error) {
-	return c.Do(method, fmt.Sprintf("%s/?url=%s", proxy, url), token, body)
+func (c *Client) DoThrough(ctx context.Context, proxy, method, url, token string, body interface{}) ([]byte, error) {
+	return c.Do(ctx, method, fmt.Sprintf("%s/?url=%s", proxy, url), token, body)
 }
 
-func New(timeout int, appName, appVersion string) *Client {
+// New creates a new HTTP client, the given certificate function (if any) provides the client certificate for mutual TLS,
+// and the secret function (if any) provides the secret to sign the requests with.
+func New(
+	timeout int,
+	appName, appVersion string,
+	certificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error),
+	secret func() string,
+) *Client {
 	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true, GetClientCertificate: certificate}
 	return &Client{
 		appName:    appName,
 		appVersion: appVersion,
+		secret:     secret,
 		e: &http.Client{
 			Transport: customTransport,
 			Timeout:   time.Duration(timeout) * time.Second,
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/labstack/echo/v4"
+)
+
+// Allowlist rejects the clients whose IP is not in the allowed networks, it allows everyone when the list is empty.
+// The networks are parsed when they are stored, see ParseNetworks.
+func Allowlist(networks func() []*net.IPNet) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			if allowed := networks(); len(allowed) > 0 && !Contains(allowed, context.RealIP()) {
+				return echo.ErrForbidden
+			}
+			return next(context)
+		}
+	}
+}
+
+// IPExtractor extracts the client IP from the connection, and from X-Forwarded-For only if it comes from a trusted proxy.
+func IPExtractor(proxies func() []*net.IPNet) echo.IPExtractor {
+	return func(r *http.Request) string {
+		return ExtractIP(r, proxies())
+	}
+}
+
+// ExtractIP extracts the client IP of the request, trusting X-Forwarded-For only when sent by the given proxies.
+func ExtractIP(r *http.Request, proxies []*net.IPNet) string {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()(r)
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, n := range proxies {
+		options = append(options, echo.TrustIPRange(n))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)(r)
+}
+
+// AllowsCaller checks if the request would still be allowed with the given allowed IPs and trusted proxies,
+// it returns the caller IP extracted with these proxies.
+func AllowsCaller(r *http.Request, allowedIps, proxies []string) (string, bool, error) {
+	allowed, err := ParseNetworks(allowedIps)
+	if err != nil {
+		return "", false, errors.WithStack(err)
+	}
+	trusted, err := ParseNetworks(proxies)
+	if err != nil {
+		return "", false, errors.WithStack(err)
+	}
+	ip := ExtractIP(r, trusted)
+	return ip, len(allowed) == 0 || Contains(allowed, ip), nil
+}
+
+// ParseNetworks parses the given CIDRs, a single IP is treated as a network of one address.
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, errors.Errorf("invalid ip '%s'", v)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cidr '%s'", v)
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// Contains checks if the given IP belongs to any of the networks.
+func Contains(networks []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range networks {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
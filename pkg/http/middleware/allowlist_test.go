@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newRequest(remoteAddr, forwardedFor string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/v1/stats", nil)
+	r.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		r.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+	}
+	return r
+}
+
+func parseTestNetworks(t *testing.T, values []string) []*net.IPNet {
+	networks, err := ParseNetworks(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return networks
+}
+
+func TestExtractIP(t *testing.T) {
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		proxies      []string
+		expected     string
+	}{
+		{"direct", "203.0.113.5:5000", "", nil, "203.0.113.5"},
+		{"spoofed without proxies", "203.0.113.5:5000", "198.51.100.1", nil, "203.0.113.5"},
+		{"spoofed from untrusted peer", "203.0.113.5:5000", "198.51.100.1", []string{"10.0.0.0/8"}, "203.0.113.5"},
+		{"trusted proxy", "10.0.0.1:5000", "198.51.100.1", []string{"10.0.0.1"}, "198.51.100.1"},
+		{"trusted proxy chain", "10.0.0.1:5000", "198.51.100.1, 10.0.0.2", []string{"10.0.0.0/24"}, "198.51.100.1"},
+		{"spoofed through proxy chain", "10.0.0.1:5000", "192.0.2.66, 198.51.100.1, 10.0.0.2", []string{"10.0.0.0/24"}, "198.51.100.1"},
+		{"untrusted hop in chain", "10.0.0.1:5000", "198.51.100.1, 172.16.0.9", []string{"10.0.0.0/24"}, "172.16.0.9"},
+		{"ipv6 trusted proxy", "[2001:db8::1]:5000", "2001:db8:ffff::5", []string{"2001:db8::/64"}, "2001:db8:ffff::5"},
+		{"ipv6 untrusted peer", "[2001:db8:1::1]:5000", "2001:db8:ffff::5", []string{"2001:db8::/64"}, "2001:db8:1::1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ip := ExtractIP(newRequest(test.remoteAddr, test.forwardedFor), parseTestNetworks(t, test.proxies))
+			if ip != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, ip)
+			}
+		})
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowed    []string
+		proxies    []string
+		remoteAddr string
+		expected   int
+	}{
+		{"empty list allows all", nil, nil, "203.0.113.5:5000", http.StatusOK},
+		{"ip", []string{"203.0.113.5"}, nil, "203.0.113.5:5000", http.StatusOK},
+		{"ip not listed", []string{"203.0.113.6"}, nil, "203.0.113.5:5000", http.StatusForbidden},
+		{"cidr", []string{"203.0.113.0/24"}, nil, "203.0.113.5:5000", http.StatusOK},
+		{"cidr not matching", []string{"198.51.100.0/24"}, nil, "203.0.113.5:5000", http.StatusForbidden},
+		{"ipv6", []string{"2001:db8::5"}, nil, "[2001:db8::5]:5000", http.StatusOK},
+		{"ipv6 cidr", []string{"2001:db8::/32"}, nil, "[2001:db8:abcd::1]:5000", http.StatusOK},
+		{"ipv6 cidr not matching", []string{"2001:db8::/32"}, nil, "[2001:db9::1]:5000", http.StatusForbidden},
+		{"ipv4 list with ipv6 client", []string{"0.0.0.0/0"}, nil, "[2001:db8::5]:5000", http.StatusForbidden},
+		{"spoofed from untrusted peer", []string{"198.51.100.1"}, []string{"10.0.0.0/8"}, "203.0.113.5:5000", http.StatusForbidden},
+		{"forwarded by trusted proxy", []string{"198.51.100.1"}, []string{"10.0.0.0/8"}, "10.0.0.1:5000", http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := echo.New()
+			proxies, allowed := parseTestNetworks(t, test.proxies), parseTestNetworks(t, test.allowed)
+			e.IPExtractor = IPExtractor(func() []*net.IPNet { return proxies })
+			e.Use(Allowlist(func() []*net.IPNet { return allowed }))
+			e.GET("/v1/stats", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, newRequest(test.remoteAddr, "198.51.100.1"))
+			if recorder.Code != test.expected {
+				t.Errorf("Expected status %d, got %d", test.expected, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestAllowsCaller(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowed    []string
+		proxies    []string
+		remoteAddr string
+		callerIp   string
+		expected   bool
+	}{
+		{"empty list", nil, nil, "203.0.113.5:5000", "203.0.113.5", true},
+		{"caller listed", []string{"203.0.113.0/24"}, nil, "203.0.113.5:5000", "203.0.113.5", true},
+		{"caller locked out", []string{"198.51.100.0/24"}, nil, "203.0.113.5:5000", "203.0.113.5", false},
+		{"caller behind new trusted proxy", []string{"198.51.100.1"}, []string{"203.0.113.5"}, "203.0.113.5:5000", "198.51.100.1", true},
+		{"proxy listed instead of caller", []string{"10.0.0.1"}, []string{"10.0.0.1"}, "10.0.0.1:5000", "198.51.100.1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ip, allowed, err := AllowsCaller(newRequest(test.remoteAddr, "198.51.100.1"), test.allowed, test.proxies)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ip != test.callerIp || allowed != test.expected {
+				t.Errorf("Expected %s allowed %v, got %s allowed %v", test.callerIp, test.expected, ip, allowed)
+			}
+		})
+	}
+
+	if _, _, err := AllowsCaller(newRequest("203.0.113.5:5000", ""), []string{"203.0.113.0/33"}, nil); err == nil {
+		t.Error("Expected an error for an invalid network")
+	}
+	if _, _, err := AllowsCaller(newRequest("203.0.113.5:5000", ""), nil, []string{"not an ip"}); err == nil {
+		t.Error("Expected an error for an invalid proxy")
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/pkg/audit"
+	"github.com/ebadidev/arch-node/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// AuditSummaryKey is the context key of the change summary recorded in the audit log.
+const AuditSummaryKey = "audit_summary"
+
+// AuditSummary sets the change summary of the current request for the audit log.
+func AuditSummary(c echo.Context, format string, args ...interface{}) {
+	c.Set(AuditSummaryKey, fmt.Sprintf(format, args...))
+}
+
+// UnauthenticatedToken is the token name recorded for the requests rejected before their token is authenticated.
+const UnauthenticatedToken = "unauthenticated"
+
+// Audit records every mutating request and its result in the audit log.
+// It is mounted before Authorize, so the rejected requests (401, 403 and 429) are recorded too.
+func Audit(a *audit.Log, l *logger.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions {
+				return next(c)
+			}
+
+			err := next(c)
+
+			status := c.Response().Status
+			var he *echo.HTTPError
+			if errors.As(err, &he) {
+				status = he.Code
+			} else if err != nil {
+				status = http.StatusInternalServerError
+			}
+
+			e := &audit.Entry{
+				Source:    "api",
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				CallerIp:  c.RealIP(),
+				RequestId: req.Header.Get(echo.HeaderXRequestID),
+				Status:    status,
+				Result:    "success",
+			}
+			if e.Token, _ = c.Get(TokenNameKey).(string); e.Token == "" {
+				e.Token = UnauthenticatedToken
+			}
+			if e.Summary, _ = c.Get(AuditSummaryKey).(string); e.Summary == "" {
+				e.Summary = fmt.Sprintf("%s %s", req.Method, c.Path())
+			}
+			if status >= 400 {
+				e.Result = "failure"
+			}
+			if err != nil {
+				e.Error = err.Error()
+			}
+
+			if recordErr := a.Record(e); recordErr != nil {
+				l.Error("audit: cannot record", zap.Error(recordErr))
+			}
+
+			return err
+		}
+	}
+}
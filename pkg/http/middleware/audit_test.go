@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ebadidev/arch-node/pkg/audit"
+	"github.com/ebadidev/arch-node/pkg/http/signature"
+	"github.com/ebadidev/arch-node/pkg/logger"
+	"github.com/labstack/echo/v4"
+)
+
+// newTestLogger creates a logger writing to the storage directory of a temp working directory.
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("storage/logs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	l := logger.New("error", "2006-01-02 15:04:05", make(chan struct{}, 10))
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestAudit(t *testing.T) {
+	l := newTestLogger(t)
+	log := audit.New(filepath.Join(t.TempDir(), "audit.log"))
+
+	e := echo.New()
+	e.Use(RequestId(l))
+	g := e.Group("/v1", Audit(log, l), Authorize(resolveTestToken, NewBanlist(1, time.Minute, time.Minute),
+		signature.NewVerifier(func() string { return "" }, time.Minute, 10)))
+	g.GET("/stats", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, Scope(ScopeStats))
+	g.POST("/users", func(c echo.Context) error {
+		AuditSummary(c, "%d users stored", 2)
+		return c.NoContent(http.StatusOK)
+	}, Scope(ScopeUsers))
+	g.DELETE("/users/:id", func(c echo.Context) error {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"message": "Validation error."})
+	}, Scope(ScopeUsers))
+	g.POST("/settings", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, Scope(ScopeAdmin))
+
+	requests := []struct {
+		method, path, token, requestId string
+	}{
+		{http.MethodGet, "/v1/stats", "stats-token", ""},
+		{http.MethodPost, "/v1/users", "users-token", "sync-1"},
+		{http.MethodDelete, "/v1/users/7", "previous-admin-token", ""},
+		{http.MethodPost, "/v1/settings", "stats-token", ""},
+		{http.MethodPost, "/v1/settings", "unknown-token", ""},
+		{http.MethodPost, "/v1/users", "users-token", ""},
+	}
+	for _, request := range requests {
+		r := httptest.NewRequest(request.method, request.path, nil)
+		r.RemoteAddr = "203.0.113.5:5000"
+		r.Header.Set(echo.HeaderAuthorization, "Bearer "+request.token)
+		if request.requestId != "" {
+			r.Header.Set(echo.HeaderXRequestID, request.requestId)
+		}
+		e.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	entries, err := log.Query(time.Time{}, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Expected the 5 mutations to be recorded, got %d", len(entries))
+	}
+
+	expected := []audit.Entry{
+		{Method: http.MethodPost, Path: "/v1/users", Token: "panel", Summary: "2 users stored", Status: 200, Result: "success"},
+		{Method: http.MethodDelete, Path: "/v1/users/7", Token: "admin-previous", Summary: "DELETE /v1/users/:id", Status: 422, Result: "failure"},
+		{Method: http.MethodPost, Path: "/v1/settings", Token: "grafana", Summary: "POST /v1/settings", Status: 403, Result: "failure"},
+		{Method: http.MethodPost, Path: "/v1/settings", Token: UnauthenticatedToken, Summary: "POST /v1/settings", Status: 401, Result: "failure"},
+		{Method: http.MethodPost, Path: "/v1/users", Token: UnauthenticatedToken, Summary: "POST /v1/users", Status: 429, Result: "failure"},
+	}
+	for _, want := range expected {
+		var got *audit.Entry
+		for _, entry := range entries {
+			if entry.Method == want.Method && entry.Path == want.Path && entry.Status == want.Status {
+				got = entry
+			}
+		}
+		if got == nil {
+			t.Errorf("Expected %s %s to be recorded", want.Method, want.Path)
+			continue
+		}
+		if got.Source != "api" || got.Token != want.Token || got.Summary != want.Summary || got.Status != want.Status ||
+			got.Result != want.Result || got.CallerIp != "203.0.113.5" || got.RequestId == "" {
+			t.Errorf("Unexpected entry %+v", got)
+		}
+		if want.Path == "/v1/users" && want.Status == 200 && got.RequestId != "sync-1" {
+			t.Errorf("Expected the request ID sync-1, got %s", got.RequestId)
+		}
+	}
+}
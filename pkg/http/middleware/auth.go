@@ -1,25 +1,79 @@
 package middleware
 
 import (
-	"github.com/labstack/echo/v4"
+	"net/http"
 	"strings"
+
+	"github.com/ebadidev/arch-node/pkg/http/signature"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ScopeStats = "stats"
+	ScopeUsers = "users"
+	ScopeAdmin = "admin"
 )
 
-func Authorize(token func() string) func(echo.HandlerFunc) echo.HandlerFunc {
+// Context keys of the authenticated token.
+const (
+	TokenNameKey  = "token_name"
+	TokenScopeKey = "token_scope"
+)
+
+// scopeRanks orders the scopes, each scope includes the permissions of the lower ones.
+var scopeRanks = map[string]int{
+	ScopeStats: 1,
+	ScopeUsers: 2,
+	ScopeAdmin: 3,
+}
+
+// Authorize authenticates the bearer token using the resolve function, which returns the token name and scope.
+// When request signing is enabled, it also rejects the unsigned, expired and replayed requests.
+func Authorize(
+	resolve func(token string) (string, string, bool),
+	bans *Banlist,
+	verifier *signature.Verifier,
+) func(echo.HandlerFunc) echo.HandlerFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(context echo.Context) error {
-			if !authorizeToken(token(), context) {
+			ip := context.RealIP()
+			if bans.Banned(ip) {
+				return echo.ErrTooManyRequests
+			}
+			name, scope, ok := authorizeToken(resolve, context)
+			if !ok {
+				bans.Fail(ip)
 				return echo.ErrUnauthorized
 			}
+			if err := verifier.Verify(context.Request()); err != nil {
+				bans.Fail(ip)
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+			bans.Succeed(ip)
+			context.Set(TokenNameKey, name)
+			context.Set(TokenScopeKey, scope)
+			return next(context)
+		}
+	}
+}
+
+// Scope rejects the requests whose token scope does not include the required one.
+func Scope(required string) func(echo.HandlerFunc) echo.HandlerFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			scope, _ := context.Get(TokenScopeKey).(string)
+			if scopeRanks[scope] < scopeRanks[required] {
+				return echo.ErrForbidden
+			}
 			return next(context)
 		}
 	}
 }
 
-func authorizeToken(token string, context echo.Context) bool {
+func authorizeToken(resolve func(token string) (string, string, bool), context echo.Context) (string, string, bool) {
 	authHeader := context.Request().Header.Get("Authorization")
 	if strings.HasPrefix(authHeader, "Bearer ") {
-		return authHeader[len("Bearer "):] == token
+		return resolve(authHeader[len("Bearer "):])
 	}
-	return false
+	return "", "", false
 }
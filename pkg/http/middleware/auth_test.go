@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ebadidev/arch-node/pkg/http/signature"
+	"github.com/labstack/echo/v4"
+)
+
+// testTokens maps the tokens to their names and scopes.
+var testTokens = map[string][2]string{
+	"current-admin-token":  {"admin", ScopeAdmin},
+	"previous-admin-token": {"admin-previous", ScopeAdmin},
+	"stats-token":          {"grafana", ScopeStats},
+	"users-token":          {"panel", ScopeUsers},
+}
+
+func resolveTestToken(token string) (string, string, bool) {
+	t, found := testTokens[token]
+	return t[0], t[1], found
+}
+
+func newTestServer(bans *Banlist, verifier *signature.Verifier) *echo.Echo {
+	e := echo.New()
+	g := e.Group("/v1", Authorize(resolveTestToken, bans, verifier))
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get(TokenNameKey).(string))
+	}
+	g.GET("/stats", handler, Scope(ScopeStats))
+	g.POST("/users", handler, Scope(ScopeUsers))
+	g.POST("/settings", handler, Scope(ScopeAdmin))
+	return e
+}
+
+func serve(e *echo.Echo, method, path, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.RemoteAddr = "203.0.113.5:5000"
+	if token != "" {
+		r.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, r)
+	return recorder
+}
+
+func TestAuthorize(t *testing.T) {
+	disabled := signature.NewVerifier(func() string { return "" }, time.Minute, 10)
+	e := newTestServer(NewBanlist(100, time.Minute, time.Minute), disabled)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		expected int
+		caller   string
+	}{
+		{"current token", http.MethodPost, "/v1/settings", "current-admin-token", http.StatusOK, "admin"},
+		{"previous token", http.MethodPost, "/v1/settings", "previous-admin-token", http.StatusOK, "admin-previous"},
+		{"scoped token", http.MethodGet, "/v1/stats", "stats-token", http.StatusOK, "grafana"},
+		{"higher scope", http.MethodGet, "/v1/stats", "users-token", http.StatusOK, "panel"},
+		{"wrong scope", http.MethodPost, "/v1/users", "stats-token", http.StatusForbidden, ""},
+		{"wrong scope for admin", http.MethodPost, "/v1/settings", "users-token", http.StatusForbidden, ""},
+		{"unknown token", http.MethodGet, "/v1/stats", "unknown-token", http.StatusUnauthorized, ""},
+		{"no token", http.MethodGet, "/v1/stats", "", http.StatusUnauthorized, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(e, test.method, test.path, test.token)
+			if recorder.Code != test.expected {
+				t.Fatalf("Expected status %d, got %d", test.expected, recorder.Code)
+			}
+			if test.caller != "" && recorder.Body.String() != test.caller {
+				t.Errorf("Expected token %s, got %s", test.caller, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthorizeSigning(t *testing.T) {
+	const secret = "0123456789abcdef0123456789abcdef"
+	e := newTestServer(NewBanlist(100, time.Minute, time.Minute), signature.NewVerifier(func() string { return secret }, time.Minute, 10))
+
+	if recorder := serve(e, http.MethodGet, "/v1/stats", "stats-token"); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected unsigned request to be rejected, got %d", recorder.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/stats", nil)
+	r.Header.Set(echo.HeaderAuthorization, "Bearer stats-token")
+	if err := signature.SignRequest(r, secret, nil); err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, r)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected signed request to be accepted, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Ban represents a temporarily banned client.
+type Ban struct {
+	IP       string    `json:"ip"`
+	Failures int       `json:"failures"`
+	Until    time.Time `json:"until"`
+}
+
+type attempts struct {
+	count int
+	since time.Time
+}
+
+// Banlist tracks failed authentication attempts per IP and bans the IPs with too many failures.
+type Banlist struct {
+	locker      *sync.Mutex
+	attempts    map[string]*attempts
+	bans        map[string]*Ban
+	maxFailures int
+	window      time.Duration
+	duration    time.Duration
+	now         func() time.Time
+}
+
+// Banned checks if the given IP is currently banned.
+func (b *Banlist) Banned(ip string) bool {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	ban, found := b.bans[ip]
+	if !found {
+		return false
+	}
+	if b.now().After(ban.Until) {
+		delete(b.bans, ip)
+		return false
+	}
+	return true
+}
+
+// Fail records a failed attempt of the given IP and bans it once it reaches the maximum failures in the window.
+func (b *Banlist) Fail(ip string) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	now := b.now()
+	b.prune(now)
+
+	a, found := b.attempts[ip]
+	if !found {
+		a = &attempts{since: now}
+		b.attempts[ip] = a
+	}
+	a.count++
+
+	if a.count >= b.maxFailures {
+		b.bans[ip] = &Ban{IP: ip, Failures: a.count, Until: now.Add(b.duration)}
+		delete(b.attempts, ip)
+	}
+}
+
+// Succeed forgets the failed attempts of the given IP.
+func (b *Banlist) Succeed(ip string) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	delete(b.attempts, ip)
+}
+
+// List returns the current bans, the earliest expiring first.
+func (b *Banlist) List() []*Ban {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+
+	b.prune(b.now())
+
+	list := make([]*Ban, 0, len(b.bans))
+	for _, ban := range b.bans {
+		list = append(list, &Ban{IP: ban.IP, Failures: ban.Failures, Until: ban.Until})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Until.Before(list[j].Until)
+	})
+	return list
+}
+
+// prune removes the expired attempts and bans, so the lists cannot grow unbounded.
+func (b *Banlist) prune(now time.Time) {
+	for ip, a := range b.attempts {
+		if now.Sub(a.since) > b.window {
+			delete(b.attempts, ip)
+		}
+	}
+	for ip, ban := range b.bans {
+		if now.After(ban.Until) {
+			delete(b.bans, ip)
+		}
+	}
+}
+
+func NewBanlist(maxFailures int, window, duration time.Duration) *Banlist {
+	return &Banlist{
+		locker:      &sync.Mutex{},
+		attempts:    map[string]*attempts{},
+		bans:        map[string]*Ban{},
+		maxFailures: maxFailures,
+		window:      window,
+		duration:    duration,
+		now:         time.Now,
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ebadidev/arch-node/pkg/http/signature"
+)
+
+// testClock is a manual clock for the banlist.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestBanlist(maxFailures int) (*Banlist, *testClock) {
+	clock := &testClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bans := NewBanlist(maxFailures, time.Minute, 10*time.Minute)
+	bans.now = clock.Now
+	return bans, clock
+}
+
+func TestBanlist(t *testing.T) {
+	const ip = "203.0.113.5"
+
+	t.Run("Failures", func(t *testing.T) {
+		bans, _ := newTestBanlist(3)
+		bans.Fail(ip)
+		bans.Fail(ip)
+		if bans.Banned(ip) {
+			t.Fatal("Expected no ban below the maximum failures")
+		}
+		bans.Fail(ip)
+		if !bans.Banned(ip) {
+			t.Fatal("Expected a ban at the maximum failures")
+		}
+		if bans.Banned("203.0.113.6") {
+			t.Error("Expected other IPs not to be banned")
+		}
+
+		list := bans.List()
+		if len(list) != 1 || list[0].IP != ip || list[0].Failures != 3 {
+			t.Errorf("Unexpected bans %+v", list)
+		}
+	})
+
+	t.Run("Window", func(t *testing.T) {
+		bans, clock := newTestBanlist(3)
+		bans.Fail(ip)
+		bans.Fail(ip)
+		clock.Advance(time.Minute + time.Second)
+		bans.Fail(ip)
+		if bans.Banned(ip) {
+			t.Error("Expected failures older than the window to be forgotten")
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		bans, _ := newTestBanlist(3)
+		bans.Fail(ip)
+		bans.Fail(ip)
+		bans.Succeed(ip)
+		bans.Fail(ip)
+		if bans.Banned(ip) {
+			t.Error("Expected a success to reset the failures")
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		bans, clock := newTestBanlist(1)
+		bans.Fail(ip)
+		clock.Advance(10 * time.Minute)
+		if !bans.Banned(ip) {
+			t.Fatal("Expected the ban to last its duration")
+		}
+		clock.Advance(time.Second)
+		if bans.Banned(ip) {
+			t.Error("Expected the ban to expire")
+		}
+		if len(bans.List()) != 0 {
+			t.Error("Expected the expired ban to be removed")
+		}
+	})
+}
+
+func TestBanlistAuthorize(t *testing.T) {
+	bans, clock := newTestBanlist(3)
+	e := newTestServer(bans, signature.NewVerifier(func() string { return "" }, time.Minute, 10))
+
+	for i := 0; i < 3; i++ {
+		if recorder := serve(e, http.MethodGet, "/v1/stats", "wrong-token"); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401, got %d", recorder.Code)
+		}
+	}
+	if recorder := serve(e, http.MethodGet, "/v1/stats", "stats-token"); recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected a banned IP to be rejected even with a valid token, got %d", recorder.Code)
+	}
+
+	clock.Advance(10*time.Minute + time.Second)
+	if recorder := serve(e, http.MethodGet, "/v1/stats", "stats-token"); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the IP to be allowed after the ban, got %d", recorder.Code)
+	}
+
+	// A success resets the failures, a wrong scope is not an authentication failure.
+	serve(e, http.MethodGet, "/v1/stats", "wrong-token")
+	serve(e, http.MethodGet, "/v1/stats", "wrong-token")
+	serve(e, http.MethodGet, "/v1/stats", "stats-token")
+	serve(e, http.MethodPost, "/v1/settings", "stats-token")
+	serve(e, http.MethodGet, "/v1/stats", "wrong-token")
+	if bans.Banned("203.0.113.5") {
+		t.Error("Expected the failures to be reset by the success")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ebadidev/arch-node/pkg/certificate"
 	"github.com/ebadidev/arch-node/pkg/logger"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
@@ -33,9 +34,15 @@ func Logger(l *logger.Logger) echo.MiddlewareFunc {
 				zap.String("user_agent", req.UserAgent()),
 			}
 
-			id := req.Header.Get(echo.HeaderXRequestID)
+			if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+				fields = append(fields, zap.String("client_cert", certificate.Identity(req.TLS.PeerCertificates[0])))
+			}
+
+			id := res.Header().Get(echo.HeaderXRequestID)
 			if id == "" {
-				id = res.Header().Get(echo.HeaderXRequestID)
+				id = req.Header.Get(echo.HeaderXRequestID)
+			}
+			if id != "" {
 				fields = append(fields, zap.String("request_id", id))
 			}
 
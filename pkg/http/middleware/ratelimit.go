@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit limits the total number of requests per second of the routes using it, regardless of the client.
+func RateLimit(limit float64, burst int) echo.MiddlewareFunc {
+	return echoMiddleware.RateLimiterWithConfig(echoMiddleware.RateLimiterConfig{
+		IdentifierExtractor: func(_ echo.Context) (string, error) {
+			return "global", nil
+		},
+		Store: echoMiddleware.NewRateLimiterMemoryStoreWithConfig(echoMiddleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(limit),
+			Burst:     burst,
+			ExpiresIn: time.Minute,
+		}),
+	})
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/ebadidev/arch-node/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/random"
+	"go.uber.org/zap"
+)
+
+// LoggerKey is the context key of the request logger.
+const LoggerKey = "logger"
+
+// requestIdPattern limits the request IDs accepted from callers, so they are safe to log and echo.
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestId assigns an ID to every request, reusing a well-formed X-Request-Id header sent by the caller.
+// The ID is echoed in the response and a logger that adds it to every entry is stored for the handlers.
+func RequestId(l *logger.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Request().Header.Get(echo.HeaderXRequestID)
+			if !requestIdPattern.MatchString(id) {
+				id = random.String(32)
+				c.Request().Header.Set(echo.HeaderXRequestID, id)
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			c.Set(LoggerKey, l.With(zap.String("request_id", id)))
+			return next(c)
+		}
+	}
+}
+
+// RequestLogger returns the logger of the current request, or nil when the RequestId middleware is not in use.
+func RequestLogger(c echo.Context) *logger.Logger {
+	l, _ := c.Get(LoggerKey).(*logger.Logger)
+	return l
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequestId(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestId(newTestLogger(t)))
+	e.GET("/", func(c echo.Context) error {
+		if RequestLogger(c) == nil {
+			t.Error("Expected the request logger to be set")
+		}
+		return c.String(http.StatusOK, c.Request().Header.Get(echo.HeaderXRequestID))
+	})
+
+	tests := []struct {
+		name     string
+		inbound  string
+		expected string
+	}{
+		{"propagated", "manager-sync-42", "manager-sync-42"},
+		{"generated", "", ""},
+		{"malformed", "bad id\nwith newline", ""},
+		{"too long", string(make([]byte, 129)), ""},
+	}
+	generated := regexp.MustCompile(`^[A-Za-z0-9]{32}$`)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.inbound != "" {
+				r.Header.Set(echo.HeaderXRequestID, test.inbound)
+			}
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, r)
+
+			id := recorder.Header().Get(echo.HeaderXRequestID)
+			if test.expected != "" && id != test.expected {
+				t.Errorf("Expected the inbound ID %s to be echoed, got %s", test.expected, id)
+			}
+			if test.expected == "" && !generated.MatchString(id) {
+				t.Errorf("Expected a generated ID, got %q", id)
+			}
+			if recorder.Body.String() != id {
+				t.Errorf("Expected the handlers to see the echoed ID %s, got %s", id, recorder.Body.String())
+			}
+		})
+	}
+}
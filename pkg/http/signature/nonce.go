@@ -0,0 +1,71 @@
+package signature
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+type nonce struct {
+	value     string
+	signedAt  time.Time
+	expiresAt time.Time
+}
+
+// NonceCache remembers the seen nonces until they expire, holding at most capacity of them.
+// Only expired nonces are dropped, unless the cache is full of unexpired ones: then the oldest are evicted, and requests signed
+// more than the allowed skew before the evicted ones are rejected, since their nonces may have been evicted.
+type NonceCache struct {
+	locker   *sync.Mutex
+	capacity int
+	skew     time.Duration
+	seen     map[string]time.Time
+	queue    []nonce
+	floor    time.Time
+}
+
+// Add records the given nonce of a request signed at signedAt, it fails if the nonce is already seen or the request is
+// signed more than the allowed skew before the evicted nonces.
+func (c *NonceCache) Add(value string, signedAt, expiresAt time.Time) error {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	now := time.Now()
+	for len(c.queue) > 0 && now.After(c.queue[0].expiresAt) {
+		delete(c.seen, c.queue[0].value)
+		c.queue = c.queue[1:]
+	}
+
+	if t, found := c.seen[value]; found && now.Before(t) {
+		return errors.New("signature: replayed request")
+	}
+	if !c.floor.IsZero() && signedAt.Before(c.floor.Add(-c.skew)) {
+		return errors.New("signature: request older than the evicted nonces")
+	}
+
+	for len(c.queue) >= c.capacity {
+		if c.queue[0].signedAt.After(c.floor) {
+			c.floor = c.queue[0].signedAt
+		}
+		delete(c.seen, c.queue[0].value)
+		c.queue = c.queue[1:]
+	}
+
+	c.seen[value] = expiresAt
+	c.queue = append(c.queue, nonce{value: value, signedAt: signedAt, expiresAt: expiresAt})
+	return nil
+}
+
+// Len returns the number of remembered nonces.
+func (c *NonceCache) Len() int {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	return len(c.queue)
+}
+
+// NewNonceCache creates a cache of the given capacity for requests signed up to skew apart.
+func NewNonceCache(capacity int, skew time.Duration) *NonceCache {
+	return &NonceCache{locker: &sync.Mutex{}, capacity: capacity, skew: skew, seen: map[string]time.Time{}}
+}
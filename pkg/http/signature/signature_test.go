@@ -0,0 +1,122 @@
+package signature
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func newSignedRequest(t *testing.T, body string) *http.Request {
+	r := httptest.NewRequest("POST", "/v1/configs?x=1", bytes.NewBufferString(body))
+	if err := SignRequest(r, testSecret, []byte(body)); err != nil {
+		t.Fatalf("Failed to sign request: %v", err)
+	}
+	return r
+}
+
+func TestVerify(t *testing.T) {
+	v := NewVerifier(func() string { return testSecret }, time.Minute, 10)
+
+	t.Run("Valid", func(t *testing.T) {
+		r := newSignedRequest(t, `{"a":1}`)
+		if err := v.Verify(r); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"a":1}` {
+			t.Errorf("Expected body to be restored, got %s", body)
+		}
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/v1/stats", nil)
+		if err := v.Verify(r); err == nil {
+			t.Error("Expected error for unsigned request")
+		}
+	})
+
+	t.Run("Tampered Body", func(t *testing.T) {
+		r := newSignedRequest(t, `{"a":1}`)
+		r.Body = io.NopCloser(bytes.NewBufferString(`{"a":2}`))
+		if err := v.Verify(r); err == nil {
+			t.Error("Expected error for tampered body")
+		}
+	})
+
+	t.Run("Replayed", func(t *testing.T) {
+		r1 := newSignedRequest(t, "")
+		r2 := r1.Clone(r1.Context())
+		r2.Body = io.NopCloser(bytes.NewBuffer(nil))
+		if err := v.Verify(r1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := v.Verify(r2); err == nil {
+			t.Error("Expected error for replayed request")
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/v1/stats", nil)
+		timestamp := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		r.Header.Set(HeaderTimestamp, timestamp)
+		r.Header.Set(HeaderNonce, "n1")
+		r.Header.Set(HeaderSignature, Sign(testSecret, "GET", "/v1/stats", timestamp, "n1", nil))
+		if err := v.Verify(r); err == nil {
+			t.Error("Expected error for expired request")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		disabled := NewVerifier(func() string { return "" }, time.Minute, 10)
+		if err := disabled.Verify(httptest.NewRequest("GET", "/v1/stats", nil)); err != nil {
+			t.Errorf("Unexpected error when signing is disabled: %v", err)
+		}
+	})
+}
+
+func TestNonceCacheCapacity(t *testing.T) {
+	c := NewNonceCache(2, time.Minute)
+	now := time.Now().Truncate(time.Second)
+	expiresAt := now.Add(time.Minute)
+
+	if err := c.Add("a", now.Add(-3*time.Second), expiresAt); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.Add("b", now, expiresAt); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.Add("c", now, expiresAt); err != nil {
+		t.Errorf("Expected the oldest nonce to be evicted when the cache is full, got %v", err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 nonces, got %d", c.Len())
+	}
+
+	// Requests signed in the same second as the evicted nonce, or earlier within the skew, are still accepted.
+	if err := c.Add("d", now.Add(-3*time.Second), expiresAt); err != nil {
+		t.Errorf("Expected a request signed in the same second as the evicted nonce to be accepted, got %v", err)
+	}
+	if err := c.Add("e", now.Add(-time.Minute), expiresAt); err != nil {
+		t.Errorf("Expected a request signed within the skew of the evicted nonces to be accepted, got %v", err)
+	}
+	if err := c.Add("f", now.Add(-2*time.Minute), expiresAt); err == nil {
+		t.Error("Expected error for a request signed more than the skew before the evicted nonces")
+	}
+	if err := c.Add("e", now.Add(-time.Minute), expiresAt); err == nil {
+		t.Error("Expected error when replaying a remembered nonce")
+	}
+
+	c.queue[0].expiresAt = time.Now().Add(-time.Second)
+	if err := c.Add("g", now, expiresAt); err != nil {
+		t.Errorf("Expected expired nonce to be evicted, got %v", err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 nonces, got %d", c.Len())
+	}
+}
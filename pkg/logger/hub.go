@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// hub broadcasts the encoded log entries to the subscribers, each one with its own minimum level.
+type hub struct {
+	locker      *sync.Mutex
+	subscribers map[chan []byte]zapcore.Level
+}
+
+func (h *hub) subscribe(buffer int, level zapcore.Level) (<-chan []byte, func()) {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+
+	ch := make(chan []byte, buffer)
+	h.subscribers[ch] = level
+
+	once := &sync.Once{}
+	return ch, func() {
+		once.Do(func() {
+			h.locker.Lock()
+			defer h.locker.Unlock()
+			delete(h.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// publish sends the line to the subscribers of its level, dropping it for the ones that are not keeping up.
+func (h *hub) publish(level zapcore.Level, line []byte) {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+
+	for ch, minimum := range h.subscribers {
+		if level < minimum {
+			continue
+		}
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// enabled checks if any subscriber wants the entries of the given level.
+func (h *hub) enabled(level zapcore.Level) bool {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+
+	for _, minimum := range h.subscribers {
+		if level >= minimum {
+			return true
+		}
+	}
+	return false
+}
+
+func newHub() *hub {
+	return &hub{locker: &sync.Mutex{}, subscribers: map[chan []byte]zapcore.Level{}}
+}
+
+// hubCore is a zap core writing the log entries to the hub.
+// It is enabled by the levels of the subscribers rather than the level of the logger, so debug entries can be streamed
+// while only warnings are written to the files.
+type hubCore struct {
+	encoder zapcore.Encoder
+	hub     *hub
+}
+
+func (c *hubCore) Enabled(level zapcore.Level) bool {
+	return c.hub.enabled(level)
+}
+
+func (c *hubCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &hubCore{encoder: c.encoder.Clone(), hub: c.hub}
+	for _, f := range fields {
+		f.AddTo(clone.encoder)
+	}
+	return clone
+}
+
+func (c *hubCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *hubCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buffer, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	line := make([]byte, buffer.Len())
+	copy(line, buffer.Bytes())
+	buffer.Free()
+
+	c.hub.publish(entry.Level, line)
+	return nil
+}
+
+func (c *hubCore) Sync() error {
+	return nil
+}
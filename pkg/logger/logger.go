@@ -12,6 +12,8 @@ type Logger struct {
 	shutdown chan struct{}
 	level    string
 	format   string
+	hub      *hub
+	atomic   zap.AtomicLevel
 }
 
 func (l *Logger) Init() (err error) {
@@ -19,6 +21,21 @@ func (l *Logger) Init() (err error) {
 	if err = level.UnmarshalText([]byte(l.level)); err != nil {
 		return errors.Wrapf(err, "invalid log level '%s'", l.level)
 	}
+	l.atomic = level
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		EncodeTime:     zapcore.TimeEncoderOfLayout(l.format),
+		EncodeDuration: zapcore.StringDurationEncoder,
+		LevelKey:       "level",
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		NameKey:        "key",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "message",
+		LineEnding:     zapcore.DefaultLineEnding,
+	}
+
+	hc := &hubCore{encoder: zapcore.NewJSONEncoder(encoderConfig), hub: l.hub}
 
 	l.e, err = zap.Config{
 		Level:             level,
@@ -28,18 +45,10 @@ func (l *Logger) Init() (err error) {
 		DisableCaller:     true,
 		OutputPaths:       []string{"./storage/logs/app-std.log"},
 		ErrorOutputPaths:  []string{"./storage/logs/app-err.log"},
-		EncoderConfig: zapcore.EncoderConfig{
-			TimeKey:        "ts",
-			EncodeTime:     zapcore.TimeEncoderOfLayout(l.format),
-			EncodeDuration: zapcore.StringDurationEncoder,
-			LevelKey:       "level",
-			EncodeLevel:    zapcore.CapitalLevelEncoder,
-			NameKey:        "key",
-			FunctionKey:    zapcore.OmitKey,
-			MessageKey:     "message",
-			LineEnding:     zapcore.DefaultLineEnding,
-		},
-	}.Build()
+		EncoderConfig:     encoderConfig,
+	}.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, hc)
+	}))
 	return errors.Wrap(err, "cannot build logger")
 }
 
@@ -60,8 +69,33 @@ func (l *Logger) Fatal(msg string, fields ...zap.Field) {
 	l.shutdown <- struct{}{}
 }
 
-func (l *Logger) With(fields ...zap.Field) *zap.Logger {
-	return l.e.With(fields...)
+// With returns a child logger that adds the given fields to every entry, it shares the level and outputs of the parent.
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	child := *l
+	child.e = l.e.With(fields...)
+	return &child
+}
+
+// SetLevel changes the minimum level of the logger without rebuilding it.
+func (l *Logger) SetLevel(level string) error {
+	if err := l.atomic.UnmarshalText([]byte(level)); err != nil {
+		return errors.Wrapf(err, "invalid log level '%s'", level)
+	}
+	return nil
+}
+
+// Subscribe streams the app log entries (JSON lines) from the given minimum level, or from the logger level when it is empty,
+// until the returned cancel function is called. Entries are dropped for a subscriber whose buffer is full.
+func (l *Logger) Subscribe(buffer int, level string) (<-chan []byte, func(), error) {
+	minimum := l.atomic.Level()
+	if level != "" {
+		var err error
+		if minimum, err = zapcore.ParseLevel(level); err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid log level '%s'", level)
+		}
+	}
+	entries, cancel := l.hub.subscribe(buffer, minimum)
+	return entries, cancel, nil
 }
 
 func (l *Logger) Close() {
@@ -73,5 +107,5 @@ func (l *Logger) Close() {
 }
 
 func New(level, format string, closer chan struct{}) (logger *Logger) {
-	return &Logger{e: nil, shutdown: closer, level: level, format: format}
+	return &Logger{e: nil, shutdown: closer, level: level, format: format, hub: newHub()}
 }
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestLogger(t *testing.T, level string) *Logger {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("storage/logs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	l := New(level, "2006-01-02 15:04:05", make(chan struct{}, 10))
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// received returns the messages delivered to the subscriber so far.
+func received(entries <-chan []byte) []string {
+	var messages []string
+	for {
+		select {
+		case entry := <-entries:
+			messages = append(messages, string(entry))
+		case <-time.After(50 * time.Millisecond):
+			return messages
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	l := newTestLogger(t, "warn")
+
+	debug, cancelDebug, err := l.Subscribe(16, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancelDebug()
+	info, cancelInfo, _ := l.Subscribe(16, "info")
+	defer cancelInfo()
+	standard, cancelStandard, _ := l.Subscribe(16, "")
+	defer cancelStandard()
+
+	l.With().Debug("debug entry")
+	l.Info("info entry")
+	l.Error("error entry")
+
+	for name, test := range map[string]struct {
+		entries  <-chan []byte
+		expected []string
+	}{
+		"debug":   {debug, []string{"debug entry", "info entry", "error entry"}},
+		"info":    {info, []string{"info entry", "error entry"}},
+		"default": {standard, []string{"error entry"}},
+	} {
+		messages := received(test.entries)
+		if len(messages) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, messages)
+			continue
+		}
+		for i, m := range test.expected {
+			if !strings.Contains(messages[i], `"message":"`+m+`"`) {
+				t.Errorf("%s: expected %s, got %s", name, m, messages[i])
+			}
+		}
+	}
+
+	content, _ := os.ReadFile("storage/logs/app-std.log")
+	if strings.Contains(string(content), "debug entry") || strings.Contains(string(content), "info entry") {
+		t.Errorf("Expected the file to keep the logger level, got %s", content)
+	}
+
+	if _, _, err = l.Subscribe(16, "verbose"); err == nil {
+		t.Error("Expected an error for an invalid level")
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	l := newTestLogger(t, "warn")
+	entries, cancel, _ := l.Subscribe(1, "debug")
+	cancel()
+	cancel()
+	if _, open := <-entries; open {
+		t.Error("Expected the channel to be closed")
+	}
+	if l.hub.enabled(zapcore.DebugLevel) {
+		t.Error("Expected no subscriber to be left")
+	}
+	l.Debug("not streamed")
+}
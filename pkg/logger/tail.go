@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Tail calls send for every line appended to the file at the given path, until the context is done.
+// It starts at the end of the file, waits for the file if it does not exist yet, and follows truncations.
+func Tail(ctx context.Context, path string, interval time.Duration, send func(line string)) error {
+	var file *os.File
+	var reader *bufio.Reader
+	var offset int64
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Only the first opened file is read from its end, files appearing later (e.g. rotated) are read entirely.
+	fromEnd := true
+	for {
+		if file == nil {
+			f, err := os.Open(path)
+			if err == nil {
+				offset = 0
+				if fromEnd {
+					if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+						_ = f.Close()
+						return errors.WithStack(err)
+					}
+				}
+				file, reader = f, bufio.NewReader(f)
+			} else if !os.IsNotExist(err) {
+				return errors.WithStack(err)
+			}
+			fromEnd = false
+		}
+
+		if file != nil {
+			if stat, err := os.Stat(path); err != nil || stat.Size() < offset {
+				// The file is removed or truncated, so it is opened again on the next tick.
+				_ = file.Close()
+				file = nil
+				continue
+			}
+		}
+
+		for file != nil {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				// The partial line is read again once it is complete.
+				if _, seekErr := file.Seek(offset, io.SeekStart); seekErr != nil {
+					return errors.WithStack(seekErr)
+				}
+				reader.Reset(file)
+				break
+			}
+			offset += int64(len(line))
+			send(strings.TrimRight(line, "\r\n"))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
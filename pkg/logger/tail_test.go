@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tailLines collects the lines sent by Tail.
+type tailLines struct {
+	locker *sync.Mutex
+	lines  []string
+}
+
+func (t *tailLines) add(line string) {
+	t.locker.Lock()
+	defer t.locker.Unlock()
+	t.lines = append(t.lines, line)
+}
+
+// wait waits until the expected lines are collected.
+func (t *tailLines) wait(expected ...string) bool {
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		t.locker.Lock()
+		equal := slices.Equal(t.lines, expected)
+		t.locker.Unlock()
+		if equal {
+			return true
+		}
+	}
+	return false
+}
+
+func appendFile(t *testing.T, path, content string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if _, err = file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func startTail(t *testing.T, path string) *tailLines {
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := &tailLines{locker: &sync.Mutex{}}
+	done := make(chan error)
+	go func() {
+		done <- Tail(ctx, path, 10*time.Millisecond, lines.add)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Unexpected tail error: %v", err)
+		}
+	})
+	// Let Tail open the file before the test appends to it.
+	time.Sleep(50 * time.Millisecond)
+	return lines
+}
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "error.log")
+	appendFile(t, path, "old line\n")
+	lines := startTail(t, path)
+
+	appendFile(t, path, "first\nsecond\r\npart")
+	if !lines.wait("first", "second") {
+		t.Fatalf("Expected the new complete lines only, got %v", lines.lines)
+	}
+
+	appendFile(t, path, "ial\n")
+	if !lines.wait("first", "second", "partial") {
+		t.Fatalf("Expected the partial line once complete, got %v", lines.lines)
+	}
+
+	if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !lines.wait("first", "second", "partial", "x") {
+		t.Fatalf("Expected the truncated file to be read again, got %v", lines.lines)
+	}
+}
+
+func TestTailMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	lines := startTail(t, path)
+
+	appendFile(t, path, "created\n")
+	if !lines.wait("created") {
+		t.Fatalf("Expected the file created later to be read entirely, got %v", lines.lines)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, "rotated\n")
+	if !lines.wait("created", "rotated") {
+		t.Fatalf("Expected the rotated file to be followed, got %v", lines.lines)
+	}
+}
@@ -0,0 +1,249 @@
+package ports
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	stateTcpListen   = "0A"
+	stateUdpUnbound  = "07"
+	socketLinkPrefix = "socket:["
+)
+
+// ErrUnavailable is returned by List when the proc directory has no socket tables, e.g. on darwin or in containers without /proc.
+var ErrUnavailable = errors.New("ports: socket tables not available")
+
+// Socket is a listening TCP or bound UDP socket of the host.
+type Socket struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Inode    uint64 `json:"-"`
+	Pid      int    `json:"pid,omitempty"`
+	Process  string `json:"process,omitempty"`
+	Owner    string `json:"owner"`
+}
+
+// Request is a port and transport (tcp or udp) to check.
+type Request struct {
+	Port     int    `json:"port" validate:"required,min=1,max=65535"`
+	Protocol string `json:"protocol" validate:"required,oneof=tcp udp"`
+}
+
+// Conflict is a requested port that is held by another socket.
+type Conflict struct {
+	Port     int       `json:"port"`
+	Protocol string    `json:"protocol"`
+	Holders  []*Socket `json:"holders"`
+}
+
+// List returns the listening sockets parsed from the given proc directory (normally /proc).
+// Owning PIDs are resolved from the process fd links that are visible to the current user.
+// It returns ErrUnavailable when none of the socket tables exists, rather than an empty list.
+func List(procPath string) ([]*Socket, error) {
+	var sockets []*Socket
+	found := false
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		list, err := parse(filepath.Join(procPath, "net", protocol), protocol)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, errors.WithStack(err)
+		}
+		found = true
+		sockets = append(sockets, list...)
+	}
+	if !found {
+		return nil, ErrUnavailable
+	}
+
+	owners := inodeOwners(procPath)
+	for _, s := range sockets {
+		if pid, found := owners[s.Inode]; found {
+			s.Pid = pid
+			if comm, err := os.ReadFile(filepath.Join(procPath, strconv.Itoa(pid), "comm")); err == nil {
+				s.Process = strings.TrimSpace(string(comm))
+			}
+		}
+	}
+
+	return sockets, nil
+}
+
+// Label sets the owner of each socket to the name of the given PID, or "other" when it is not among them.
+func Label(sockets []*Socket, pids map[int]string) {
+	for _, s := range sockets {
+		s.Owner = "other"
+		if name, found := pids[s.Pid]; found && s.Pid != 0 {
+			s.Owner = name
+		}
+	}
+}
+
+// Check returns every requested port that is held by a socket of the same transport, skipping the ignored owners.
+func Check(sockets []*Socket, requests []*Request, ignoredOwners ...string) []*Conflict {
+	conflicts := make([]*Conflict, 0)
+	seen := map[string]bool{}
+	for _, r := range requests {
+		key := r.Protocol + ":" + strconv.Itoa(r.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var holders []*Socket
+		for _, s := range sockets {
+			if s.Port != r.Port || strings.TrimSuffix(s.Protocol, "6") != r.Protocol {
+				continue
+			}
+			ignored := false
+			for _, o := range ignoredOwners {
+				ignored = ignored || s.Owner == o
+			}
+			if !ignored {
+				holders = append(holders, s)
+			}
+		}
+		if len(holders) > 0 {
+			conflicts = append(conflicts, &Conflict{Port: r.Port, Protocol: r.Protocol, Holders: holders})
+		}
+	}
+	return conflicts
+}
+
+// Probe checks the requested ports by binding them on all addresses, for hosts where List is unavailable.
+// The holders of the taken ports are unknown, so the conflicts have no holders.
+func Probe(requests []*Request) []*Conflict {
+	conflicts := make([]*Conflict, 0)
+	seen := map[string]bool{}
+	for _, r := range requests {
+		address := ":" + strconv.Itoa(r.Port)
+		if seen[r.Protocol+address] {
+			continue
+		}
+		seen[r.Protocol+address] = true
+
+		var err error
+		if r.Protocol == "udp" {
+			var c net.PacketConn
+			if c, err = net.ListenPacket("udp", address); err == nil {
+				_ = c.Close()
+			}
+		} else {
+			var l net.Listener
+			if l, err = net.Listen("tcp", address); err == nil {
+				_ = l.Close()
+			}
+		}
+		if err != nil {
+			conflicts = append(conflicts, &Conflict{Port: r.Port, Protocol: r.Protocol, Holders: []*Socket{}})
+		}
+	}
+	return conflicts
+}
+
+// parse reads a /proc/net/{tcp,udp}[6] table and returns its listening (TCP) or unconnected (UDP) sockets.
+func parse(path, protocol string) ([]*Socket, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	state := stateTcpListen
+	if strings.HasPrefix(protocol, "udp") {
+		state = stateUdpUnbound
+	}
+
+	sockets := make([]*Socket, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != state {
+			continue
+		}
+
+		hexIp, hexPort, found := strings.Cut(fields[1], ":")
+		if !found {
+			continue
+		}
+		ip, err := parseIp(hexIp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid address in %s", path)
+		}
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid port in %s", path)
+		}
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+
+		sockets = append(sockets, &Socket{
+			Protocol: protocol,
+			Address:  ip.String(),
+			Port:     int(port),
+			Inode:    inode,
+		})
+	}
+
+	return sockets, errors.WithStack(scanner.Err())
+}
+
+// parseIp decodes a kernel address, stored as host-order (little-endian) 32-bit words.
+func parseIp(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(b) != net.IPv4len && len(b) != net.IPv6len {
+		return nil, errors.Errorf("unexpected address length %d", len(b))
+	}
+	ip := make(net.IP, len(b))
+	for i := 0; i < len(b); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(b[i:]))
+	}
+	return ip, nil
+}
+
+// inodeOwners maps socket inodes to the PIDs holding them, processes that cannot be inspected are skipped.
+func inodeOwners(procPath string) map[uint64]int {
+	owners := map[uint64]int{}
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return owners
+	}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		fdPath := filepath.Join(procPath, e.Name(), "fd")
+		fds, err := os.ReadDir(fdPath)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdPath, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, socketLinkPrefix) {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, socketLinkPrefix), "]"), 10, 64)
+			if err == nil {
+				owners[inode] = pid
+			}
+		}
+	}
+	return owners
+}
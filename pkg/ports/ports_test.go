@@ -0,0 +1,133 @@
+package ports
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testTcp = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0D53 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 00000000:01BB 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:0D53 0100007F:A000 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 100 0 0 10 0
+`
+
+const testTcp6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+`
+
+const testUdp = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000:01BB 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 3001 2 0000000000000000 0
+`
+
+func newTestProc(t *testing.T) string {
+	root := t.TempDir()
+	files := map[string]string{"net/tcp": testTcp, "net/tcp6": testTcp6, "net/udp": testUdp, "42/comm": "xray\n"}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "42", "fd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("socket:[1002]", filepath.Join(root, "42", "fd", "3")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestList(t *testing.T) {
+	sockets, err := List(newTestProc(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sockets) != 4 {
+		t.Fatalf("Expected 4 sockets, got %d", len(sockets))
+	}
+
+	expected := []Socket{
+		{Protocol: "tcp", Address: "127.0.0.1", Port: 3411},
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 443, Pid: 42, Process: "xray"},
+		{Protocol: "tcp6", Address: "::", Port: 8080},
+		{Protocol: "udp", Address: "0.0.0.0", Port: 443},
+	}
+	for i, e := range expected {
+		s := sockets[i]
+		if s.Protocol != e.Protocol || s.Address != e.Address || s.Port != e.Port || s.Pid != e.Pid || s.Process != e.Process {
+			t.Errorf("Socket %d: expected %+v, got %+v", i, e, *s)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	sockets, err := List(newTestProc(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	Label(sockets, map[int]string{42: "xray"})
+
+	conflicts := Check(sockets, []*Request{
+		{Port: 3411, Protocol: "tcp"},
+		{Port: 3411, Protocol: "udp"},
+		{Port: 8080, Protocol: "tcp"},
+		{Port: 443, Protocol: "udp"},
+		{Port: 443, Protocol: "tcp"},
+		{Port: 8080, Protocol: "tcp"},
+	}, "xray")
+
+	if len(conflicts) != 3 {
+		t.Fatalf("Expected 3 conflicts, got %d", len(conflicts))
+	}
+	for i, port := range []int{3411, 8080, 443} {
+		if conflicts[i].Port != port {
+			t.Errorf("Conflict %d: expected port %d, got %d", i, port, conflicts[i].Port)
+		}
+	}
+	if conflicts[2].Protocol != "udp" || conflicts[2].Holders[0].Owner != "other" {
+		t.Errorf("Expected the UDP 443 conflict held by another process, got %+v", conflicts[2])
+	}
+}
+
+func TestListUnavailable(t *testing.T) {
+	if _, err := List(t.TempDir()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable without socket tables, got %v", err)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tcp, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = tcp.Close()
+	}()
+	udp, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = udp.Close()
+	}()
+	tcpPort := tcp.Addr().(*net.TCPAddr).Port
+	udpPort := udp.LocalAddr().(*net.UDPAddr).Port
+
+	conflicts := Probe([]*Request{
+		{Port: tcpPort, Protocol: "tcp"},
+		{Port: udpPort, Protocol: "udp"},
+		{Port: tcpPort, Protocol: "tcp"},
+	})
+	if len(conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %d", len(conflicts))
+	}
+	if conflicts[0].Port != tcpPort || conflicts[0].Protocol != "tcp" || conflicts[1].Port != udpPort || conflicts[1].Protocol != "udp" {
+		t.Errorf("Unexpected conflicts %+v %+v", conflicts[0], conflicts[1])
+	}
+}
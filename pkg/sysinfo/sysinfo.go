@@ -0,0 +1,262 @@
+package sysinfo
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// startedAt approximates the process start time.
+var startedAt = time.Now()
+
+type Info struct {
+	Hostname    string       `json:"hostname"`
+	OS          string       `json:"os"`
+	Distro      string       `json:"distro,omitempty"`
+	Kernel      string       `json:"kernel,omitempty"`
+	Arch        string       `json:"arch"`
+	Cpu         *Cpu         `json:"cpu"`
+	Memory      *Memory      `json:"memory,omitempty"`
+	Disk        *Disk        `json:"disk,omitempty"`
+	Interfaces  []*Interface `json:"interfaces,omitempty"`
+	Files       *Files       `json:"files,omitempty"`
+	Uptime      int64        `json:"uptime"`
+	AppUptime   int64        `json:"app_uptime"`
+	PublicIps   []string     `json:"public_ips"`
+	LocalIps    []string     `json:"local_ips"`
+	AppVersion  string       `json:"app_version"`
+	XrayVersion string       `json:"xray_version,omitempty"`
+}
+
+type Cpu struct {
+	Count int       `json:"count"`
+	Load  []float64 `json:"load,omitempty"`
+}
+
+type Memory struct {
+	Total     uint64 `json:"total"`
+	Available uint64 `json:"available"`
+	SwapTotal uint64 `json:"swap_total"`
+	SwapFree  uint64 `json:"swap_free"`
+}
+
+type Disk struct {
+	Path  string `json:"path"`
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`
+	Used  uint64 `json:"used"`
+}
+
+type Interface struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses,omitempty"`
+	RxBytes   uint64   `json:"rx_bytes"`
+	RxPackets uint64   `json:"rx_packets"`
+	RxErrors  uint64   `json:"rx_errors"`
+	RxDropped uint64   `json:"rx_dropped"`
+	TxBytes   uint64   `json:"tx_bytes"`
+	TxPackets uint64   `json:"tx_packets"`
+	TxErrors  uint64   `json:"tx_errors"`
+	TxDropped uint64   `json:"tx_dropped"`
+}
+
+type Files struct {
+	Process   int    `json:"process"`
+	System    uint64 `json:"system"`
+	SystemMax uint64 `json:"system_max"`
+}
+
+// Collect gathers the host information from the given proc directory (normally /proc), the parts that are not available
+// are left empty. The public IPs are the configured ones, the interfaces only have private addresses behind NAT.
+func Collect(procPath, storagePath string, publicIps []string) *Info {
+	info := &Info{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Cpu:       &Cpu{Count: runtime.NumCPU()},
+		AppUptime: int64(time.Since(startedAt).Seconds()),
+		PublicIps: append([]string{}, publicIps...),
+		LocalIps:  []string{},
+	}
+
+	info.Hostname, _ = os.Hostname()
+	info.Distro, _ = distro()
+	if kernel, err := os.ReadFile(filepath.Join(procPath, "sys", "kernel", "osrelease")); err == nil {
+		info.Kernel = strings.TrimSpace(string(kernel))
+	}
+	info.Cpu.Load, _ = load(procPath)
+	info.Memory, _ = memory(procPath)
+	info.Disk, _ = disk(storagePath)
+	info.Interfaces, _ = interfaces(procPath)
+	info.Files, _ = files(procPath)
+	info.Uptime, _ = uptime(procPath)
+
+	for _, i := range info.Interfaces {
+		for _, address := range i.Addresses {
+			ip, _, err := net.ParseCIDR(address)
+			if err != nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			if ip.IsPrivate() {
+				info.LocalIps = append(info.LocalIps, ip.String())
+			}
+		}
+	}
+
+	return info
+}
+
+func distro() (string, error) {
+	values, err := readKeyValues("/etc/os-release", "=")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return strings.Trim(values["PRETTY_NAME"], `"`), nil
+}
+
+func load(procPath string) ([]float64, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "loadavg"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) < 3 {
+		return nil, errors.New("invalid loadavg")
+	}
+	values := make([]float64, 3)
+	for i := range values {
+		if values[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return values, nil
+}
+
+func memory(procPath string) (*Memory, error) {
+	values, err := readKeyValues(filepath.Join(procPath, "meminfo"), ":")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	kb := func(key string) uint64 {
+		v, _ := strconv.ParseUint(strings.TrimSuffix(values[key], " kB"), 10, 64)
+		return v * 1024
+	}
+	return &Memory{
+		Total:     kb("MemTotal"),
+		Available: kb("MemAvailable"),
+		SwapTotal: kb("SwapTotal"),
+		SwapFree:  kb("SwapFree"),
+	}, nil
+}
+
+func disk(path string) (*Disk, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d := &Disk{
+		Path:  path,
+		Total: stat.Blocks * uint64(stat.Bsize),
+		Free:  stat.Bavail * uint64(stat.Bsize),
+	}
+	d.Used = d.Total - stat.Bfree*uint64(stat.Bsize)
+	return d, nil
+}
+
+func interfaces(procPath string) ([]*Interface, error) {
+	file, err := os.Open(filepath.Join(procPath, "net", "dev"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	list := make([]*Interface, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, counters, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 16 {
+			continue
+		}
+		n := make([]uint64, 16)
+		for i := range n {
+			n[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		i := &Interface{
+			Name:    strings.TrimSpace(name),
+			RxBytes: n[0], RxPackets: n[1], RxErrors: n[2], RxDropped: n[3],
+			TxBytes: n[8], TxPackets: n[9], TxErrors: n[10], TxDropped: n[11],
+		}
+		if ni, err := net.InterfaceByName(i.Name); err == nil {
+			if addresses, err := ni.Addrs(); err == nil {
+				for _, a := range addresses {
+					i.Addresses = append(i.Addresses, a.String())
+				}
+			}
+		}
+		list = append(list, i)
+	}
+
+	return list, errors.WithStack(scanner.Err())
+}
+
+func files(procPath string) (*Files, error) {
+	entries, err := os.ReadDir(filepath.Join(procPath, "self", "fd"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f := &Files{Process: len(entries)}
+
+	if content, err := os.ReadFile(filepath.Join(procPath, "sys", "fs", "file-nr")); err == nil {
+		if fields := strings.Fields(string(content)); len(fields) == 3 {
+			f.System, _ = strconv.ParseUint(fields[0], 10, 64)
+			f.SystemMax, _ = strconv.ParseUint(fields[2], 10, 64)
+		}
+	}
+	return f, nil
+}
+
+func uptime(procPath string) (int64, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "uptime"))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return 0, errors.New("invalid uptime")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	return int64(seconds), errors.WithStack(err)
+}
+
+// readKeyValues reads a file of "key<separator>value" lines.
+func readKeyValues(path, separator string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if key, value, found := strings.Cut(scanner.Text(), separator); found {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return values, errors.WithStack(scanner.Err())
+}
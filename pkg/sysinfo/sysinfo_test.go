@@ -0,0 +1,58 @@
+package sysinfo
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	info := Collect("testdata/proc", t.TempDir(), []string{"203.0.113.10"})
+
+	if info.Kernel != "6.1.0-test" {
+		t.Errorf("Expected kernel 6.1.0-test, got %s", info.Kernel)
+	}
+	if !slices.Equal(info.Cpu.Load, []float64{0.52, 0.58, 0.59}) {
+		t.Errorf("Expected load [0.52 0.58 0.59], got %v", info.Cpu.Load)
+	}
+	expectedMemory := Memory{Total: 2000000 * 1024, Available: 1500000 * 1024, SwapTotal: 1000000 * 1024, SwapFree: 800000 * 1024}
+	if info.Memory == nil || *info.Memory != expectedMemory {
+		t.Errorf("Expected memory %+v, got %+v", expectedMemory, info.Memory)
+	}
+	if info.Disk == nil || info.Disk.Total == 0 {
+		t.Errorf("Expected the disk usage of the storage, got %+v", info.Disk)
+	}
+	if info.Uptime != 3600 {
+		t.Errorf("Expected uptime 3600, got %d", info.Uptime)
+	}
+	expectedFiles := Files{Process: 3, System: 1024, SystemMax: 9223372036854775807}
+	if info.Files == nil || *info.Files != expectedFiles {
+		t.Errorf("Expected files %+v, got %+v", expectedFiles, info.Files)
+	}
+
+	if len(info.Interfaces) != 2 {
+		t.Fatalf("Expected 2 interfaces, got %d", len(info.Interfaces))
+	}
+	i := info.Interfaces[0]
+	if i.Name != "test0" || i.RxBytes != 1000 || i.RxPackets != 10 || i.RxErrors != 1 || i.RxDropped != 2 ||
+		i.TxBytes != 2000 || i.TxPackets != 20 || i.TxErrors != 3 || i.TxDropped != 4 {
+		t.Errorf("Unexpected interface %+v", i)
+	}
+	if info.Interfaces[1].Name != "test1" || info.Interfaces[1].RxBytes != 3000 {
+		t.Errorf("Unexpected interface %+v", info.Interfaces[1])
+	}
+
+	if !slices.Equal(info.PublicIps, []string{"203.0.113.10"}) {
+		t.Errorf("Expected the configured public IPs, got %v", info.PublicIps)
+	}
+}
+
+func TestCollectMissingProc(t *testing.T) {
+	info := Collect(t.TempDir(), t.TempDir(), nil)
+
+	if info.Kernel != "" || info.Cpu.Load != nil || info.Memory != nil || info.Interfaces != nil || info.Files != nil {
+		t.Errorf("Expected the proc parts to be empty, got %+v", info)
+	}
+	if info.PublicIps == nil || len(info.PublicIps) != 0 {
+		t.Errorf("Expected no public IPs, got %v", info.PublicIps)
+	}
+}
@@ -0,0 +1,98 @@
+package xray
+
+import (
+	"net"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// MakeMux creates mux settings with the given concurrency for TCP and XUDP (UDP over mux), 0 keeps the Xray defaults.
+func (c *Config) MakeMux(concurrency, xudpConcurrency int) *Mux {
+	return &Mux{
+		Enabled:         true,
+		Concurrency:     concurrency,
+		XudpConcurrency: xudpConcurrency,
+	}
+}
+
+// AddOutboundChain adds outbounds that are dialed through each other, in the order the traffic goes through them:
+// the first one connects directly and each next one is sent through the previous one (e.g. node A then node B).
+// It returns the last outbound, rules route the traffic to its tag.
+func (c *Config) AddOutboundChain(hops ...*Outbound) *Outbound {
+	if len(hops) == 0 {
+		return nil
+	}
+	for i, hop := range hops {
+		if i > 0 {
+			hop.ProxySettings = &ProxySettings{Tag: hops[i-1].Tag}
+		}
+		c.Outbounds = append(c.Outbounds, hop)
+	}
+	return hops[len(hops)-1]
+}
+
+// proxyTag returns the tag of the outbound the given outbound is dialed through, if any.
+func proxyTag(o *Outbound) string {
+	if o.ProxySettings != nil && o.ProxySettings.Tag != "" {
+		return o.ProxySettings.Tag
+	}
+	if o.StreamSettings != nil && o.StreamSettings.SocketSettings != nil {
+		return o.StreamSettings.SocketSettings.DialerProxy
+	}
+	return ""
+}
+
+// validateChains checks the sendThrough addresses and that proxySettings and sockopt.dialerProxy reference existing outbounds without cycles.
+func (c *Config) validateChains() error {
+	next := map[string]string{}
+	for _, o := range c.Outbounds {
+		if err := validateSendThrough(o.SendThrough); err != nil {
+			return errors.Wrapf(err, "invalid outbound %s", o.Tag)
+		}
+		if o.ProxySettings != nil && o.StreamSettings != nil && o.StreamSettings.SocketSettings != nil &&
+			o.StreamSettings.SocketSettings.DialerProxy != "" {
+			return errors.Errorf("outbound %s cannot have both proxySettings and sockopt.dialerProxy", o.Tag)
+		}
+		tag := proxyTag(o)
+		if tag == "" {
+			continue
+		}
+		if c.FindOutbound(tag) == nil {
+			return errors.Errorf("outbound %s is proxied through outbound %s, which does not exist", o.Tag, tag)
+		}
+		next[o.Tag] = tag
+	}
+
+	// Each outbound has at most one proxy, so following them from an outbound either ends or loops.
+	for _, o := range c.Outbounds {
+		seen := map[string]bool{}
+		for tag := o.Tag; tag != ""; tag = next[tag] {
+			if seen[tag] {
+				return errors.Errorf("outbound %s has a proxy cycle through %s", o.Tag, tag)
+			}
+			seen[tag] = true
+		}
+	}
+	return nil
+}
+
+// validateSendThrough checks the value is "origin", "srcip" (the inbound address the connection came to), a CIDR to pick random
+// addresses from, or an IP address. Whether the address belongs to this server is checked when the config is stored, not here,
+// so a config saved on another server (e.g. a restored backup) stays valid.
+func validateSendThrough(value string) error {
+	switch {
+	case value == "", value == "origin", value == "srcip":
+		return nil
+	case strings.Contains(value, "/"):
+		if _, _, err := net.ParseCIDR(value); err != nil {
+			return errors.Errorf("invalid sendThrough CIDR '%s'", value)
+		}
+		return nil
+	}
+
+	if net.ParseIP(value) == nil {
+		return errors.Errorf("invalid sendThrough '%s', expected an IP address", value)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package xray
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestChainValidation(t *testing.T) {
+	const uuid = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+	newChain := func() *Config {
+		config := NewConfig("info")
+		last := config.AddOutboundChain(
+			config.MakeShadowsocksOutbound("node-a", "a.example.com", "password", "aes-128-gcm", 8388),
+			config.MakeVlessOutbound("node-b", "b.example.com", 443, uuid, "tcp"),
+		)
+		last.Mux = config.MakeMux(8, 16)
+		return config
+	}
+
+	t.Run("Chain", func(t *testing.T) {
+		config := newChain()
+		if b := config.FindOutbound("node-b"); b.ProxySettings == nil || b.ProxySettings.Tag != "node-a" {
+			t.Fatalf("Expected node-b to be dialed through node-a, got %+v", b.ProxySettings)
+		}
+		if config.FindOutbound("node-a").ProxySettings != nil {
+			t.Error("Expected the first hop to connect directly")
+		}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Missing Proxy", func(t *testing.T) {
+		config := newChain()
+		config.FindOutbound("node-b").ProxySettings.Tag = "node-c"
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for a missing proxy outbound")
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		config := newChain()
+		config.FindOutbound("node-a").StreamSettings.SocketSettings = &SocketSettings{DialerProxy: "node-b"}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for a proxy cycle")
+		}
+
+		config = NewConfig("info")
+		config.Outbounds[0].ProxySettings = &ProxySettings{Tag: "out"}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for an outbound proxied through itself")
+		}
+	})
+
+	t.Run("Proxy And Dialer", func(t *testing.T) {
+		config := newChain()
+		config.FindOutbound("node-b").StreamSettings.SocketSettings = &SocketSettings{DialerProxy: "node-a"}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for both proxySettings and dialerProxy")
+		}
+	})
+
+	t.Run("Mux", func(t *testing.T) {
+		config := newChain()
+		config.FindOutbound("node-b").Mux.Concurrency = 2000
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for a too large concurrency")
+		}
+		config.FindOutbound("node-b").Mux = &Mux{Concurrency: -1, XudpProxyUDP443: "drop"}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for an unknown xudpProxyUDP443")
+		}
+	})
+
+	t.Run("Send Through", func(t *testing.T) {
+		for _, value := range []string{"127.0.0.1", "192.0.2.123", "0.0.0.0", "origin", "srcip", "2001:db8::/64"} {
+			config := NewConfig("info")
+			config.Outbounds[0].SendThrough = value
+			if err := config.Validate(); err != nil {
+				t.Errorf("Unexpected validation error for %s: %v", value, err)
+			}
+		}
+		for _, value := range []string{"example.com", "10.0.0.0/33", "192.0.2"} {
+			config := NewConfig("info")
+			config.Outbounds[0].SendThrough = value
+			if err := config.Validate(); err == nil {
+				t.Errorf("Expected validation error for %s", value)
+			}
+		}
+	})
+}
+
+func TestMuxJson(t *testing.T) {
+	var mux Mux
+	if err := json.Unmarshal([]byte(`{"enabled":false,"concurrency":-1}`), &mux); err != nil {
+		t.Fatal(err)
+	}
+	output, err := json.Marshal(mux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != `{"enabled":false,"concurrency":-1}` {
+		t.Errorf("Unexpected JSON %s", output)
+	}
+}
@@ -2,6 +2,9 @@ package xray
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+
 	"github.com/cockroachdb/errors"
 	"github.com/go-playground/validator/v10"
 )
@@ -10,6 +13,8 @@ type Log struct {
 	LogLevel string `json:"loglevel" validate:"required"`
 	Access   string `json:"access,omitempty"`
 	Error    string `json:"error,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Client struct {
@@ -20,15 +25,43 @@ type Client struct {
 	AlterId  int    `json:"alterId,omitempty"`    // For VMess
 	Level    int    `json:"level,omitempty"`      // User level
 	Security string `json:"security,omitempty"`   // For VMess/VLESS security
+	Flow     string `json:"flow,omitempty" validate:"omitempty,oneof=xtls-rprx-vision"` // For VLESS XTLS
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type InboundSettings struct {
-	Address    string    `json:"address,omitempty"`
-	Clients    []*Client `json:"clients,omitempty" validate:"omitempty,dive"`
-	Network    string    `json:"network,omitempty"`
-	Method     string    `json:"method,omitempty"`
-	Password   string    `json:"password,omitempty"`
-	Decryption string    `json:"decryption,omitempty"` // For VLESS
+	Address    string      `json:"address,omitempty"`
+	Clients    []*Client   `json:"clients,omitempty" validate:"omitempty,dive"`
+	Network    string      `json:"network,omitempty"`
+	Method     string      `json:"method,omitempty"`
+	Password   string      `json:"password,omitempty"`
+	Decryption string      `json:"decryption,omitempty"` // For VLESS
+	Fallbacks  []*Fallback `json:"fallbacks,omitempty" validate:"omitempty,dive"` // For VLESS/Trojan
+
+	// Dokodemo-door forwards to address:port, or to the original destination with followRedirect
+	Port           int  `json:"port,omitempty" validate:"min=0,max=65535"`
+	FollowRedirect bool `json:"followRedirect,omitempty"`
+
+	// SOCKS and HTTP proxies
+	Auth             string     `json:"auth,omitempty" validate:"omitempty,oneof=noauth password"` // For SOCKS
+	Accounts         []*Account `json:"accounts,omitempty" validate:"omitempty,dive"`
+	UDP              bool       `json:"udp,omitempty"`                          // For SOCKS
+	IP               string     `json:"ip,omitempty" validate:"omitempty,ip"`   // For SOCKS, the address of UDP replies
+	AllowTransparent bool       `json:"allowTransparent,omitempty"`             // For HTTP
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Fallback forwards the VLESS or Trojan connections that fail authentication, matched by SNI, ALPN and HTTP path.
+type Fallback struct {
+	Name string       `json:"name,omitempty"`
+	Alpn string       `json:"alpn,omitempty" validate:"omitempty,oneof=h2 http/1.1"`
+	Path string       `json:"path,omitempty" validate:"omitempty,startswith=/"`
+	Dest FallbackDest `json:"dest" validate:"required"`
+	Xver int          `json:"xver,omitempty" validate:"min=0,max=2"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Inbound struct {
@@ -37,7 +70,22 @@ type Inbound struct {
 	Protocol       string           `json:"protocol" validate:"required"`
 	Settings       *InboundSettings `json:"settings" validate:"required"`
 	StreamSettings *StreamSettings  `json:"streamSettings,omitempty"`
+	Sniffing       *Sniffing        `json:"sniffing,omitempty"`
 	Tag            string           `json:"tag" validate:"required"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Sniffing reads the destination domain from the inbound traffic, so domain rules match connections made to IP addresses.
+// With RouteOnly the sniffed domain is used for routing only and the connection keeps its original destination.
+type Sniffing struct {
+	Enabled         bool     `json:"enabled"`
+	DestOverride    []string `json:"destOverride,omitempty" validate:"omitempty,dive,oneof=http tls quic fakedns fakedns+others"`
+	MetadataOnly    bool     `json:"metadataOnly,omitempty"`
+	RouteOnly       bool     `json:"routeOnly,omitempty"`
+	DomainsExcluded []string `json:"domainsExcluded,omitempty" validate:"omitempty,dive,required"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type OutboundServer struct {
@@ -50,11 +98,36 @@ type OutboundServer struct {
 	AlterId  int    `json:"alterId,omitempty"`    // For VMess
 	Level    int    `json:"level,omitempty"`      // User level
 	Security string `json:"security,omitempty"`   // For VMess/VLESS/Trojan
+	Flow     string `json:"flow,omitempty" validate:"omitempty,oneof=xtls-rprx-vision xtls-rprx-vision-udp443"` // For VLESS XTLS
+	Users    []*Account `json:"users,omitempty" validate:"omitempty,dive"` // For SOCKS/HTTP credentials
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Account is a SOCKS or HTTP proxy user, the level applies to outbound users only.
+type Account struct {
+	User  string `json:"user" validate:"required"`
+	Pass  string `json:"pass" validate:"required"`
+	Level int    `json:"level,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type OutboundSettings struct {
 	Servers []*OutboundServer `json:"servers,omitempty" validate:"omitempty,dive"`
 	Vnext   []*VnextServer    `json:"vnext,omitempty" validate:"omitempty,dive"`
+
+	// Freedom
+	DomainStrategy string    `json:"domainStrategy,omitempty"`
+	Redirect       string    `json:"redirect,omitempty"`
+	Fragment       *Fragment `json:"fragment,omitempty"`
+	Noises         []*Noise  `json:"noises,omitempty" validate:"omitempty,dive"`
+	ProxyProtocol  int       `json:"proxyProtocol,omitempty" validate:"min=0,max=2"`
+
+	// Blackhole
+	Response *BlackholeResponse `json:"response,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // VnextServer represents a VMess outbound server configuration
@@ -62,6 +135,8 @@ type VnextServer struct {
 	Address string      `json:"address" validate:"required"`
 	Port    int         `json:"port" validate:"required,min=1,max=65536"`
 	Users   []*VmessUser `json:"users" validate:"required,dive"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // VmessUser represents a VMess user configuration for outbound connections
@@ -70,6 +145,9 @@ type VmessUser struct {
 	AlterId  int    `json:"alterId,omitempty"`
 	Level    int    `json:"level,omitempty"`
 	Security string `json:"security,omitempty"`
+	Flow     string `json:"flow,omitempty" validate:"omitempty,oneof=xtls-rprx-vision xtls-rprx-vision-udp443"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type StreamSettings struct {
@@ -91,18 +169,24 @@ type StreamSettings struct {
 	
 	// Socket settings
 	SocketSettings     *SocketSettings      `json:"sockopt,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // TCP with HTTP header masquerading
 type TcpSettings struct {
 	AcceptProxyProtocol bool              `json:"acceptProxyProtocol,omitempty"`
 	Header             *TcpHeaderObject   `json:"header,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type TcpHeaderObject struct {
 	Type     string                    `json:"type"`
 	Request  *HttpRequestObject        `json:"request,omitempty"`
 	Response *HttpResponseObject       `json:"response,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type HttpRequestObject struct {
@@ -110,6 +194,8 @@ type HttpRequestObject struct {
 	Method  string              `json:"method,omitempty"`
 	Path    []string            `json:"path,omitempty"`
 	Headers map[string][]string `json:"headers,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type HttpResponseObject struct {
@@ -117,6 +203,8 @@ type HttpResponseObject struct {
 	Status  string              `json:"status,omitempty"`
 	Reason  string              `json:"reason,omitempty"`
 	Headers map[string][]string `json:"headers,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // WebSocket
@@ -126,12 +214,16 @@ type WebSocketSettings struct {
 	Host               string `json:"host,omitempty"`
 	HeartbeatPeriod    int    `json:"heartbeatPeriod,omitempty"`
 	CustomHost         string `json:"custom_host,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // HTTP (Legacy HTTP transport)
 type HttpSettings struct {
 	Host []string `json:"host,omitempty"`
 	Path string   `json:"path,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // gRPC
@@ -145,6 +237,8 @@ type GrpcSettings struct {
 	HealthCheckTimeout   int    `json:"health_check_timeout,omitempty"`
 	PermitWithoutStream  bool   `json:"permit_without_stream,omitempty"`
 	InitialWindowsSize   int    `json:"initial_windows_size,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // KCP
@@ -159,11 +253,15 @@ type KcpSettings struct {
 	WriteBufferSize    int               `json:"writeBufferSize,omitempty"`
 	Header             *KcpHeaderObject  `json:"header,omitempty"`
 	Seed               string            `json:"seed,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type KcpHeaderObject struct {
 	Type   string `json:"type"`
 	Domain string `json:"domain,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // HTTP Upgrade
@@ -172,6 +270,8 @@ type HttpUpgradeSettings struct {
 	Host               string `json:"host,omitempty"`
 	Path               string `json:"path,omitempty"`
 	CustomHost         string `json:"custom_host,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // XHTTP
@@ -183,6 +283,8 @@ type XhttpSettings struct {
 	NoSSEHeader        bool   `json:"noSSEHeader,omitempty"`
 	NoGRPCHeader       bool   `json:"noGRPCHeader,omitempty"`
 	Mode               string `json:"mode,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Socket Settings (common to all transports)
@@ -195,6 +297,9 @@ type SocketSettings struct {
 	TcpWindowClamp      int    `json:"tcpWindowClamp,omitempty"`
 	TcpKeepAliveIdle    int    `json:"tcpKeepAliveIdle,omitempty"`
 	TcpMptcp            bool   `json:"tcpMptcp,omitempty"`
+	DialerProxy         string `json:"dialerProxy,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // TLS Settings
@@ -207,6 +312,8 @@ type TlsSettings struct {
 	CurvePreferences    string   `json:"curvepreferences,omitempty"`
 	Alpn                []string `json:"alpn,omitempty"`
 	ServerNameToVerify  string   `json:"serverNameToVerify,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // REALITY Settings
@@ -223,48 +330,179 @@ type RealitySettings struct {
 	Fingerprint  string   `json:"fingerprint,omitempty"`
 	SpiderX      string   `json:"spiderx,omitempty"`
 	PublicKey    string   `json:"publickey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Outbound struct {
 	Protocol       string            `json:"protocol" validate:"required"`
 	Tag            string            `json:"tag" validate:"required"`
+	SendThrough    string            `json:"sendThrough,omitempty"`
 	Settings       *OutboundSettings `json:"settings,omitempty"`
 	StreamSettings *StreamSettings   `json:"streamSettings,omitempty"`
+	ProxySettings  *ProxySettings    `json:"proxySettings,omitempty"`
+	Mux            *Mux              `json:"mux,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
+// ProxySettings sends the outbound traffic through another outbound, e.g. to reach a relay through the previous hop.
+// Unless transportLayer is set, the stream settings of the outbound are not used.
+type ProxySettings struct {
+	Tag            string `json:"tag" validate:"required"`
+	TransportLayer bool   `json:"transportLayer,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Mux multiplexes the connections of an outbound over a few ones, a negative concurrency disables it.
+type Mux struct {
+	Enabled         bool   `json:"enabled"`
+	Concurrency     int    `json:"concurrency,omitempty" validate:"min=-1,max=1024"`
+	XudpConcurrency int    `json:"xudpConcurrency,omitempty" validate:"min=-1,max=1024"`
+	XudpProxyUDP443 string `json:"xudpProxyUDP443,omitempty" validate:"omitempty,oneof=reject allow skip"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DNS is the built-in DNS server used by the routing (IPIfNonMatch and IPOnDemand) and by freedom outbounds resolving domains.
+// The hosts keys use the routing domain syntax, see validateDns.
 type DNS struct {
-	Servers []string `json:"servers" validate:"required"`
+	Servers       []*DNSServer       `json:"servers" validate:"required,dive"`
+	Hosts         map[string]DNSHost `json:"hosts,omitempty"`
+	ClientIp      string             `json:"clientIp,omitempty" validate:"omitempty,ip"`
+	QueryStrategy string             `json:"queryStrategy,omitempty" validate:"omitempty,oneof=UseIP UseIPv4 UseIPv6 UseSystem"`
+	DisableCache  bool               `json:"disableCache,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type API struct {
 	Tag      string   `json:"tag" validate:"required"`
 	Services []string `json:"services" validate:"required"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
+// PolicyLevels is the policy of the users with a level. Timeouts are in seconds and bufferSize is in KB per connection
+// (0 disables buffering, -1 is unlimited), they are pointers so an unset option keeps the Xray default.
 type PolicyLevels struct {
-	StatsUserUplink   bool `json:"statsUserUplink"`
-	StatsUserDownlink bool `json:"statsUserDownlink"`
+	Handshake         *int `json:"handshake,omitempty" validate:"omitempty,min=0"`
+	ConnIdle          *int `json:"connIdle,omitempty" validate:"omitempty,min=0"`
+	UplinkOnly        *int `json:"uplinkOnly,omitempty" validate:"omitempty,min=0"`
+	DownlinkOnly      *int `json:"downlinkOnly,omitempty" validate:"omitempty,min=0"`
+	BufferSize        *int `json:"bufferSize,omitempty" validate:"omitempty,min=-1"`
+	StatsUserUplink   bool `json:"statsUserUplink,omitempty"`
+	StatsUserDownlink bool `json:"statsUserDownlink,omitempty"`
+	StatsUserOnline   bool `json:"statsUserOnline,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
+type PolicySystem struct {
+	StatsInboundUplink    bool `json:"statsInboundUplink,omitempty"`
+	StatsInboundDownlink  bool `json:"statsInboundDownlink,omitempty"`
+	StatsOutboundUplink   bool `json:"statsOutboundUplink,omitempty"`
+	StatsOutboundDownlink bool `json:"statsOutboundDownlink,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Policy maps user levels ("0", "1", ...) to their policies, clients reference them with their level.
 type Policy struct {
-	Levels map[string]map[string]bool `json:"levels"`
-	System map[string]bool            `json:"system"`
+	Levels map[string]*PolicyLevels `json:"levels" validate:"dive,required"`
+	System *PolicySystem            `json:"system,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
+// Rule routes the connections matching all its matchers to an outbound or a balancer, see validateRule for the matcher syntax.
 type Rule struct {
-	InboundTag  []string `json:"inboundTag" validate:"required"`
-	OutboundTag string   `json:"outboundTag,omitempty"`
-	BalancerTag string   `json:"balancerTag,omitempty"`
-	Domain      []string `json:"domain,omitempty"`
+	InboundTag    []string          `json:"inboundTag,omitempty"`
+	OutboundTag   string            `json:"outboundTag,omitempty"`
+	BalancerTag   string            `json:"balancerTag,omitempty"`
+	Domain        []string          `json:"domain,omitempty"`
+	IP            []string          `json:"ip,omitempty"`
+	Port          PortList          `json:"port,omitempty"`
+	SourcePort    PortList          `json:"sourcePort,omitempty"`
+	Source        []string          `json:"source,omitempty"`
+	Network       string            `json:"network,omitempty"`
+	Protocol      []string          `json:"protocol,omitempty" validate:"omitempty,dive,oneof=http tls quic bittorrent"`
+	User          []string          `json:"user,omitempty"`
+	Attrs         map[string]string `json:"attrs,omitempty"`
+	RuleTag       string            `json:"ruleTag,omitempty"`
+	DomainMatcher string            `json:"domainMatcher,omitempty" validate:"omitempty,oneof=hybrid linear"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type RoutingSettings struct {
 	Rules []*Rule `json:"rules" validate:"required,dive"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Balancer struct {
-	Tag      string   `json:"tag" validate:"required"`
-	Selector []string `json:"selector"`
+	Tag         string            `json:"tag" validate:"required"`
+	Selector    []string          `json:"selector"`
+	Strategy    *BalancerStrategy `json:"strategy,omitempty"`
+	FallbackTag string            `json:"fallbackTag,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type BalancerStrategy struct {
+	Type     string                    `json:"type" validate:"oneof=random roundRobin leastPing leastLoad"`
+	Settings *BalancerStrategySettings `json:"settings,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// BalancerStrategySettings holds the leastLoad tuning options, durations are Xray duration strings (e.g. "1s").
+type BalancerStrategySettings struct {
+	Expected  int               `json:"expected,omitempty" validate:"min=0"`
+	MaxRTT    string            `json:"maxRTT,omitempty"`
+	Tolerance float64           `json:"tolerance,omitempty" validate:"min=0,max=1"`
+	Baselines []string          `json:"baselines,omitempty"`
+	Costs     []*BalancerWeight `json:"costs,omitempty" validate:"omitempty,dive"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type BalancerWeight struct {
+	Regexp bool    `json:"regexp,omitempty"`
+	Match  string  `json:"match" validate:"required"`
+	Value  float64 `json:"value" validate:"min=0"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Observatory periodically probes the selected outbounds, it feeds the leastPing strategy.
+type Observatory struct {
+	SubjectSelector   []string `json:"subjectSelector" validate:"required,min=1"`
+	ProbeUrl          string   `json:"probeUrl,omitempty" validate:"omitempty,url"`
+	ProbeInterval     string   `json:"probeInterval,omitempty"`
+	EnableConcurrency bool     `json:"enableConcurrency,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// BurstObservatory probes the selected outbounds in bursts, it feeds the leastLoad strategy.
+type BurstObservatory struct {
+	SubjectSelector []string    `json:"subjectSelector" validate:"required,min=1"`
+	PingConfig      *PingConfig `json:"pingConfig" validate:"required"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type PingConfig struct {
+	Destination  string `json:"destination,omitempty" validate:"omitempty,url"`
+	Connectivity string `json:"connectivity,omitempty" validate:"omitempty,url"`
+	Interval     string `json:"interval,omitempty"`
+	Sampling     int    `json:"sampling,omitempty" validate:"min=0"`
+	Timeout      string `json:"timeout,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Routing struct {
@@ -272,21 +510,29 @@ type Routing struct {
 	DomainMatcher  string      `json:"domainMatcher" validate:"required"`
 	Rules          []*Rule     `json:"rules,omitempty" validate:"omitempty,dive"`
 	Balancers      []*Balancer `json:"balancers,omitempty" validate:"omitempty,dive"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Reverse struct {
 	Bridges []*ReverseItem `json:"bridges,omitempty"  validate:"omitempty,dive"`
 	Portals []*ReverseItem `json:"portals,omitempty"  validate:"omitempty,dive"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type ReverseItem struct {
 	Tag    string `json:"tag"  validate:"required"`
 	Domain string `json:"domain"  validate:"required"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Metadata struct {
 	UpdatedAt string `json:"updatedAt"`
 	UpdatedBy string `json:"UpdatedBy"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Config struct {
@@ -299,7 +545,15 @@ type Config struct {
 	Policy    *Policy                `json:"policy" validate:"required"`
 	Routing   *Routing               `json:"routing" validate:"required"`
 	Reverse   *Reverse               `json:"reverse,omitempty"`
-	Metadata  *Metadata              `json:"_metadata,omitempty"`
+
+	Observatory      *Observatory      `json:"observatory,omitempty"`
+	BurstObservatory *BurstObservatory `json:"burstObservatory,omitempty"`
+
+	FakeDNS FakeDNS `json:"fakedns,omitempty" validate:"omitempty,dive"`
+
+	Metadata *Metadata `json:"_metadata,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 func (c *Config) MakeShadowsocksInbound(tag, password, method, network string, port int, clients []*Client) *Inbound {
@@ -314,6 +568,7 @@ func (c *Config) MakeShadowsocksInbound(tag, password, method, network string, p
 			Method:   method,
 			Network:  network,
 		},
+		Sniffing: c.MakeSniffing(),
 	}
 }
 
@@ -372,6 +627,7 @@ func (c *Config) MakeVlessInbound(tag string, port int, uuid string, network str
 		Listen:   "0.0.0.0",
 		Port:     port,
 		Settings: settings,
+		Sniffing: c.MakeSniffing(),
 	}
 	
 	// Apply stream settings if provided
@@ -405,6 +661,40 @@ func (c *Config) MakeVlessOutbound(tag, address string, port int, uuid, network
 	}
 }
 
+// MakeVlessVisionInbound creates a VLESS inbound with the XTLS Vision flow, the stream settings must use raw TCP with TLS or REALITY
+// (e.g. AddRealityToStreamSettings(nil, ...)). Connections failing authentication are forwarded to the fallbacks, if any.
+func (c *Config) MakeVlessVisionInbound(tag string, port int, uuid string, streamSettings *StreamSettings, fallbacks []*Fallback) *Inbound {
+	inbound := c.MakeVlessInbound(tag, port, uuid, "tcp", streamSettings)
+	for _, client := range inbound.Settings.Clients {
+		client.Flow = FlowVision
+	}
+	inbound.Settings.Fallbacks = fallbacks
+	return inbound
+}
+
+// MakeVlessVisionOutbound creates a VLESS outbound with the XTLS Vision flow over the given TLS or REALITY stream settings
+func (c *Config) MakeVlessVisionOutbound(tag, address string, port int, uuid string, streamSettings *StreamSettings) *Outbound {
+	outbound := c.MakeVlessOutbound(tag, address, port, uuid, "tcp")
+	for _, server := range outbound.Settings.Servers {
+		server.Flow = FlowVision
+	}
+	if streamSettings != nil {
+		outbound.StreamSettings = streamSettings
+	}
+	return outbound
+}
+
+// MakeFallback creates a fallback to dest (a port, "address:port" or Unix socket path) for the connections matching alpn and path,
+// xver sets the PROXY protocol version sent to dest (0 to disable)
+func (c *Config) MakeFallback(dest, alpn, path string, xver int) *Fallback {
+	return &Fallback{
+		Dest: FallbackDest(dest),
+		Alpn: alpn,
+		Path: path,
+		Xver: xver,
+	}
+}
+
 // VMess Protocol Support  
 func (c *Config) MakeVmessInbound(tag string, port int, uuid, encryption string, streamSettings *StreamSettings) *Inbound {
 	// Validate compatibility for VMess protocol
@@ -454,6 +744,7 @@ func (c *Config) MakeVmessInbound(tag string, port int, uuid, encryption string,
 		Port:           port,
 		Settings:       settings,
 		StreamSettings: streamSettings,
+		Sniffing:       c.MakeSniffing(),
 	}
 }
 
@@ -505,6 +796,7 @@ func (c *Config) MakeTrojanInbound(tag string, port int, password, network strin
 		Listen:   "0.0.0.0",
 		Port:     port,
 		Settings: settings,
+		Sniffing: c.MakeSniffing(),
 	}
 }
 
@@ -527,6 +819,19 @@ func (c *Config) MakeTrojanOutbound(tag, address string, port int, password, net
 	}
 }
 
+// MakeSniffing creates the sniffing settings applied by the inbound builders: enabled for the given protocols
+// (http, tls and quic by default) and used for routing only. Set Inbound.Sniffing to override or nil to disable it.
+func (c *Config) MakeSniffing(destOverride ...string) *Sniffing {
+	if len(destOverride) == 0 {
+		destOverride = []string{"http", "tls", "quic"}
+	}
+	return &Sniffing{
+		Enabled:      true,
+		DestOverride: destOverride,
+		RouteOnly:    true,
+	}
+}
+
 func (c *Config) FindInbound(tag string) *Inbound {
 	for _, inbound := range c.Inbounds {
 		if inbound.Tag == tag {
@@ -554,6 +859,33 @@ func (c *Config) FindBalancer(tag string) *Balancer {
 	return nil
 }
 
+// Transports returns the layer 4 protocols ("tcp", "udp") the inbound listens on.
+// KCP, QUIC and XHTTP over HTTP/3 (TLS with the single ALPN "h3") listen on UDP.
+func (i *Inbound) Transports() []string {
+	if s := i.StreamSettings; s != nil {
+		switch s.Network {
+		case "kcp", "mkcp", "quic":
+			return []string{"udp"}
+		case "xhttp", "splithttp":
+			if s.TlsSettings != nil && len(s.TlsSettings.Alpn) == 1 && s.TlsSettings.Alpn[0] == "h3" {
+				return []string{"udp"}
+			}
+		}
+	}
+	if i.Settings != nil && i.Settings.Network != "" {
+		var transports []string
+		for _, n := range strings.Split(i.Settings.Network, ",") {
+			if n = strings.TrimSpace(n); n == "tcp" || n == "udp" {
+				transports = append(transports, n)
+			}
+		}
+		if len(transports) > 0 {
+			return transports
+		}
+	}
+	return []string{"tcp"}
+}
+
 func (c *Config) Validate() error {
 	if c.FindInbound("api") == nil {
 		return errors.New("xray: config: api inbound not found")
@@ -563,6 +895,26 @@ func (c *Config) Validate() error {
 	if err := c.validateProtocolSpecific(); err != nil {
 		return errors.WithStack(err)
 	}
+
+	if err := c.validateRules(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := c.validateDns(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := c.validatePolicy(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := c.validateChains(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := c.validateBalancers(); err != nil {
+		return errors.WithStack(err)
+	}
 	
 	return errors.WithStack(validator.New(validator.WithRequiredStructEnabled()).Struct(c))
 }
@@ -576,19 +928,38 @@ func (c *Config) validateProtocolSpecific() error {
 				if err := c.validateClient(client, inbound.Protocol); err != nil {
 					return errors.Wrapf(err, "invalid client for protocol %s in inbound %s", inbound.Protocol, inbound.Tag)
 				}
+				if err := c.validateLevel(client.Level); err != nil {
+					return errors.Wrapf(err, "invalid client %s in inbound %s", client.Email, inbound.Tag)
+				}
+				if err := validateFlow(client.Flow, inbound.Protocol, inbound.StreamSettings); err != nil {
+					return errors.Wrapf(err, "invalid client %s in inbound %s", client.Email, inbound.Tag)
+				}
 			}
 		}
+		if err := validateFallbacks(inbound); err != nil {
+			return errors.Wrapf(err, "invalid fallbacks in inbound %s", inbound.Tag)
+		}
+		if err := validateInboundSettings(inbound); err != nil {
+			return errors.Wrapf(err, "invalid settings for protocol %s in inbound %s", inbound.Protocol, inbound.Tag)
+		}
 	}
 	
 	// Validate outbounds
 	for _, outbound := range c.Outbounds {
 		if outbound.Settings != nil {
+			if err := validateOutboundSettings(outbound.Settings, outbound.Protocol); err != nil {
+				return errors.Wrapf(err, "invalid settings for protocol %s in outbound %s", outbound.Protocol, outbound.Tag)
+			}
+
 			// Validate servers (for Shadowsocks, VLESS, Trojan)
 			if outbound.Settings.Servers != nil {
 				for _, server := range outbound.Settings.Servers {
 					if err := c.validateServer(server, outbound.Protocol); err != nil {
 						return errors.Wrapf(err, "invalid server for protocol %s in outbound %s", outbound.Protocol, outbound.Tag)
 					}
+					if err := validateFlow(server.Flow, outbound.Protocol, outbound.StreamSettings); err != nil {
+						return errors.Wrapf(err, "invalid server in outbound %s", outbound.Tag)
+					}
 				}
 			}
 			
@@ -598,6 +969,11 @@ func (c *Config) validateProtocolSpecific() error {
 					if err := c.validateVnext(vnext, outbound.Protocol); err != nil {
 						return errors.Wrapf(err, "invalid vnext for protocol %s in outbound %s", outbound.Protocol, outbound.Tag)
 					}
+					for _, user := range vnext.Users {
+						if err := validateFlow(user.Flow, outbound.Protocol, outbound.StreamSettings); err != nil {
+							return errors.Wrapf(err, "invalid vnext user in outbound %s", outbound.Tag)
+						}
+					}
 				}
 			}
 		}
@@ -628,8 +1004,12 @@ func (c *Config) validateClient(client *Client, protocol string) error {
 		if client.Password == "" {
 			return errors.New("trojan client requires password field")
 		}
-	case "dokodemo-door", "freedom", "blackhole", "socks", "http":
-		// System protocols - no client validation needed
+	case "socks", "http":
+		return errors.Errorf("%s inbound does not take clients, use accounts", protocol)
+	case "dokodemo-door":
+		return errors.New("dokodemo-door inbound does not take clients")
+	case "freedom", "blackhole":
+		// Outbound-only protocols - no client validation needed
 		break
 	default:
 		// Allow unknown protocols to pass validation
@@ -660,9 +1040,16 @@ func (c *Config) validateServer(server *OutboundServer, protocol string) error {
 		if server.Password == "" {
 			return errors.New("trojan server requires password field")
 		}
-	case "freedom", "blackhole", "socks", "http":
-		// System protocols - no server validation needed
-		break
+	case "freedom", "blackhole":
+		// Settings-only protocols, see validateOutboundSettings
+		return errors.Errorf("%s outbound does not take servers", protocol)
+	case "socks", "http":
+		// Credentials are optional
+		for _, user := range server.Users {
+			if err := c.validateLevel(user.Level); err != nil {
+				return errors.Wrapf(err, "invalid %s user %s", protocol, user.User)
+			}
+		}
 	default:
 		// Allow unknown protocols to pass validation
 		break
@@ -685,6 +1072,17 @@ func (c *Config) validateVnext(vnext *VnextServer, protocol string) error {
 	return nil
 }
 
+// Summary returns a short description of the config, for logs and audit records.
+func (c *Config) Summary() string {
+	clients := 0
+	for _, i := range c.Inbounds {
+		if i.Settings != nil {
+			clients += len(i.Settings.Clients)
+		}
+	}
+	return fmt.Sprintf("%d inbounds, %d outbounds, %d clients", len(c.Inbounds), len(c.Outbounds), clients)
+}
+
 func (c *Config) Equals(other *Config) bool {
 	json1, err := json.Marshal(c)
 	if err != nil {
@@ -725,7 +1123,7 @@ func NewConfig(logLevel string) *Config {
 			},
 		},
 		DNS: &DNS{
-			Servers: []string{"8.8.8.8", "8.8.4.4", "localhost"},
+			Servers: []*DNSServer{{Address: "8.8.8.8"}, {Address: "8.8.4.4"}, {Address: "localhost"}},
 		},
 		Stats: map[string]interface{}{},
 		API: &API{
@@ -733,17 +1131,17 @@ func NewConfig(logLevel string) *Config {
 			Services: []string{"StatsService"},
 		},
 		Policy: &Policy{
-			Levels: map[string]map[string]bool{
+			Levels: map[string]*PolicyLevels{
 				"0": {
-					"statsUserUplink":   true,
-					"statsUserDownlink": true,
+					StatsUserUplink:   true,
+					StatsUserDownlink: true,
 				},
 			},
-			System: map[string]bool{
-				"statsInboundUplink":    true,
-				"statsInboundDownlink":  true,
-				"statsOutboundUplink":   true,
-				"statsOutboundDownlink": true,
+			System: &PolicySystem{
+				StatsInboundUplink:    true,
+				StatsInboundDownlink:  true,
+				StatsOutboundUplink:   true,
+				StatsOutboundDownlink: true,
 			},
 		},
 		Routing: &Routing{
@@ -983,6 +1381,7 @@ func (c *Config) SanitizeStreamSettingsForProtocol(protocol string, streamSettin
 		TlsSettings:         streamSettings.TlsSettings,
 		RealitySettings:     streamSettings.RealitySettings,
 		SocketSettings:      streamSettings.SocketSettings,
+		Extra:               streamSettings.Extra,
 	}
 	
 	switch protocol {
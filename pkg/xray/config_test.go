@@ -0,0 +1,32 @@
+package xray
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInboundTransports(t *testing.T) {
+	tests := map[string]struct {
+		inbound  *Inbound
+		expected []string
+	}{
+		"tcp":            {&Inbound{StreamSettings: &StreamSettings{Network: "tcp"}}, []string{"tcp"}},
+		"no stream":      {&Inbound{}, []string{"tcp"}},
+		"kcp":            {&Inbound{StreamSettings: &StreamSettings{Network: "kcp"}}, []string{"udp"}},
+		"mkcp":           {&Inbound{StreamSettings: &StreamSettings{Network: "mkcp"}}, []string{"udp"}},
+		"quic":           {&Inbound{StreamSettings: &StreamSettings{Network: "quic"}}, []string{"udp"}},
+		"xhttp h3":       {&Inbound{StreamSettings: &StreamSettings{Network: "xhttp", TlsSettings: &TlsSettings{Alpn: []string{"h3"}}}}, []string{"udp"}},
+		"xhttp h2":       {&Inbound{StreamSettings: &StreamSettings{Network: "xhttp", TlsSettings: &TlsSettings{Alpn: []string{"h3", "h2"}}}}, []string{"tcp"}},
+		"xhttp":          {&Inbound{StreamSettings: &StreamSettings{Network: "xhttp"}}, []string{"tcp"}},
+		"network":        {&Inbound{Settings: &InboundSettings{Network: "tcp, udp"}}, []string{"tcp", "udp"}},
+		"network udp":    {&Inbound{Settings: &InboundSettings{Network: "udp"}}, []string{"udp"}},
+		"network others": {&Inbound{Settings: &InboundSettings{Network: "unix"}}, []string{"tcp"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if transports := test.inbound.Transports(); !slices.Equal(transports, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, transports)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package xray
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// dnsSchemes are the URL schemes of the DNS-over-HTTPS, DNS-over-QUIC and DNS-over-TCP servers, "+local" queries bypass the routing.
+var dnsSchemes = []string{"https", "https+local", "h2c", "h2c+local", "quic+local", "tcp", "tcp+local"}
+
+// DNSServer is a name server, Xray accepts a plain address or an object with the options below.
+type DNSServer struct {
+	Address       string   `json:"address" validate:"required"`
+	Port          int      `json:"port,omitempty" validate:"min=0,max=65535"`
+	Domains       []string `json:"domains,omitempty"`
+	ExpectIPs     []string `json:"expectIPs,omitempty"`
+	SkipFallback  bool     `json:"skipFallback,omitempty"`
+	QueryStrategy string   `json:"queryStrategy,omitempty" validate:"omitempty,oneof=UseIP UseIPv4 UseIPv6 UseSystem"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (s *DNSServer) UnmarshalJSON(content []byte) (err error) {
+	var address string
+	if err := json.Unmarshal(content, &address); err == nil {
+		*s = DNSServer{Address: address}
+		return nil
+	}
+	type alias DNSServer
+	s.Extra, err = unmarshalExtra(content, (*alias)(s))
+	return err
+}
+
+// MarshalJSON writes a server with only an address as a plain string, like the manager and the Xray docs do.
+func (s DNSServer) MarshalJSON() ([]byte, error) {
+	if s.Port == 0 && len(s.Domains) == 0 && len(s.ExpectIPs) == 0 && !s.SkipFallback && s.QueryStrategy == "" && len(s.Extra) == 0 {
+		return json.Marshal(s.Address)
+	}
+	type alias DNSServer
+	return marshalExtra(alias(s), s.Extra)
+}
+
+// DNSHost is the address list a hosts entry maps to, Xray accepts a single address or an array.
+// A single address is written as a string.
+type DNSHost []string
+
+func (h *DNSHost) UnmarshalJSON(content []byte) error {
+	var address string
+	if err := json.Unmarshal(content, &address); err == nil {
+		*h = DNSHost{address}
+		return nil
+	}
+	var addresses []string
+	if err := json.Unmarshal(content, &addresses); err != nil {
+		return errors.New("hosts address must be a string or an array of strings")
+	}
+	*h = addresses
+	return nil
+}
+
+func (h DNSHost) MarshalJSON() ([]byte, error) {
+	if len(h) == 1 {
+		return json.Marshal(h[0])
+	}
+	return json.Marshal([]string(h))
+}
+
+// FakeDNSPool is a range of fake IPs returned by the "fakedns" server, poolSize is the number of domains it remembers.
+type FakeDNSPool struct {
+	IPPool   string `json:"ipPool" validate:"required,cidr"`
+	PoolSize int    `json:"poolSize" validate:"required,min=1"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// FakeDNS is the list of fake IP pools, Xray accepts a single pool object or an array. It is written as an array.
+type FakeDNS []*FakeDNSPool
+
+func (f *FakeDNS) UnmarshalJSON(content []byte) error {
+	if content = bytes.TrimSpace(content); len(content) > 0 && content[0] == '{' {
+		var pool FakeDNSPool
+		if err := json.Unmarshal(content, &pool); err != nil {
+			return errors.WithStack(err)
+		}
+		*f = FakeDNS{&pool}
+		return nil
+	}
+	var pools []*FakeDNSPool
+	if err := json.Unmarshal(content, &pools); err != nil {
+		return errors.WithStack(err)
+	}
+	*f = pools
+	return nil
+}
+
+// MakeDnsServer creates a name server used for the given domains, its answers are accepted only if they match expectIPs.
+func (c *Config) MakeDnsServer(address string, port int, domains, expectIPs []string) *DNSServer {
+	return &DNSServer{
+		Address:   address,
+		Port:      port,
+		Domains:   domains,
+		ExpectIPs: expectIPs,
+	}
+}
+
+// EnableFakeDns adds a fake IP pool and puts the "fakedns" server first, so domains are answered with fake IPs.
+// The inbounds need "fakedns" in their sniffing destOverride to map the fake IPs back to the domains.
+func (c *Config) EnableFakeDns(ipPool string, poolSize int) *FakeDNSPool {
+	pool := &FakeDNSPool{IPPool: ipPool, PoolSize: poolSize}
+	c.FakeDNS = append(c.FakeDNS, pool)
+	if !c.usesFakeDnsServer() {
+		c.DNS.Servers = append([]*DNSServer{{Address: "fakedns"}}, c.DNS.Servers...)
+	}
+	return pool
+}
+
+func (c *Config) usesFakeDnsServer() bool {
+	if c.DNS == nil {
+		return false
+	}
+	return slices.ContainsFunc(c.DNS.Servers, func(s *DNSServer) bool {
+		return s != nil && strings.EqualFold(s.Address, "fakedns")
+	})
+}
+
+// validateDns checks the name servers, the hosts mapping and the fake IP pools, and that sniffing with
+// "fakedns" has a pool to look the fake IPs up in (Xray adds a default pool when the "fakedns" server is used).
+func (c *Config) validateDns() error {
+	if c.DNS != nil {
+		for i, server := range c.DNS.Servers {
+			if server == nil {
+				continue
+			}
+			if err := validateDnsServer(server); err != nil {
+				return errors.Wrapf(err, "invalid dns server %d", i)
+			}
+		}
+		for domain, addresses := range c.DNS.Hosts {
+			if err := validateDomainMatcher(domain); err != nil {
+				return errors.Wrap(err, "invalid dns hosts")
+			}
+			if len(addresses) == 0 {
+				return errors.Errorf("invalid dns hosts, %s has no address", domain)
+			}
+			for _, address := range addresses {
+				if net.ParseIP(address) == nil && !validHostname(address) {
+					return errors.Errorf("invalid dns hosts, %s maps to invalid address '%s'", domain, address)
+				}
+			}
+		}
+	}
+
+	for _, pool := range c.FakeDNS {
+		if pool == nil {
+			continue
+		}
+		_, network, err := net.ParseCIDR(pool.IPPool)
+		if err != nil {
+			return errors.Errorf("invalid fakedns ipPool '%s'", pool.IPPool)
+		}
+		ones, bits := network.Mask.Size()
+		if bits-ones < 32 && pool.PoolSize > 1<<(bits-ones) {
+			return errors.Errorf("fakedns poolSize %d is larger than ipPool %s", pool.PoolSize, pool.IPPool)
+		}
+	}
+
+	if len(c.FakeDNS) == 0 && !c.usesFakeDnsServer() {
+		for _, inbound := range c.Inbounds {
+			if inbound.Sniffing == nil {
+				continue
+			}
+			for _, d := range inbound.Sniffing.DestOverride {
+				if d == "fakedns" || d == "fakedns+others" {
+					return errors.Errorf("inbound %s sniffs %s, but no fakedns pool or server is configured", inbound.Tag, d)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateDnsServer checks the address is "localhost", "fakedns", an IP, a hostname or a URL with a supported scheme,
+// and the domains and expectIPs use the routing rule syntax.
+func validateDnsServer(server *DNSServer) error {
+	address := server.Address
+	switch {
+	case strings.Contains(address, "://"):
+		u, err := url.Parse(address)
+		if err != nil || u.Host == "" {
+			return errors.Errorf("invalid address '%s'", address)
+		}
+		if !slices.Contains(dnsSchemes, strings.ToLower(u.Scheme)) {
+			return errors.Errorf("invalid address '%s', the scheme must be one of %s", address, strings.Join(dnsSchemes, ", "))
+		}
+	case strings.EqualFold(address, "localhost"), strings.EqualFold(address, "fakedns"), net.ParseIP(address) != nil:
+	case !validHostname(address):
+		return errors.Errorf("invalid address '%s'", address)
+	}
+
+	for _, domain := range server.Domains {
+		if err := validateDomainMatcher(domain); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, ip := range server.ExpectIPs {
+		if ip == "*" {
+			continue
+		}
+		if err := validateIpMatcher(ip); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// validHostname reports whether the value is a domain name made of letters, digits, hyphens and underscores.
+func validHostname(value string) bool {
+	if value == "" || len(value) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(value, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
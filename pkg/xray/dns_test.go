@@ -0,0 +1,141 @@
+package xray
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDnsValidation(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		config := NewConfig("info")
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Servers", func(t *testing.T) {
+		config := NewConfig("info")
+		config.DNS.Servers = []*DNSServer{
+			config.MakeDnsServer("https://1.1.1.1/dns-query", 0, nil, nil),
+			config.MakeDnsServer("223.5.5.5", 53, []string{"geosite:cn", "domain:example.ir"}, []string{"geoip:cn", "*"}),
+			config.MakeDnsServer("tcp+local://8.8.8.8:53", 0, []string{"full:api.example.com"}, nil),
+			config.MakeDnsServer("dns.example.com", 0, nil, nil),
+			{Address: "localhost", SkipFallback: true, QueryStrategy: "UseIPv4"},
+		}
+		config.DNS.Hosts = map[string]DNSHost{
+			"domain:example.com": {"10.0.0.1", "10.0.0.2"},
+			"geosite:ads":        {"127.0.0.1"},
+			"full:cdn.example":   {"origin.example.com"},
+		}
+		config.DNS.ClientIp = "203.0.113.1"
+		config.DNS.QueryStrategy = "UseIP"
+		config.DNS.DisableCache = true
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for name, modify := range map[string]func(c *Config){
+			"scheme":         func(c *Config) { c.DNS.Servers[0].Address = "udp://8.8.8.8" },
+			"address":        func(c *Config) { c.DNS.Servers[0].Address = "8.8.8.8:53" },
+			"empty address":  func(c *Config) { c.DNS.Servers[0].Address = "" },
+			"port":           func(c *Config) { c.DNS.Servers[0].Port = 70000 },
+			"domain":         func(c *Config) { c.DNS.Servers[0].Domains = []string{"regexp:("} },
+			"expect ip":      func(c *Config) { c.DNS.Servers[0].ExpectIPs = []string{"10.0.0.0/33"} },
+			"query strategy": func(c *Config) { c.DNS.QueryStrategy = "IPv4" },
+			"client ip":      func(c *Config) { c.DNS.ClientIp = "example.com" },
+			"host domain":    func(c *Config) { c.DNS.Hosts = map[string]DNSHost{"geosite:": {"127.0.0.1"}} },
+			"host address":   func(c *Config) { c.DNS.Hosts = map[string]DNSHost{"example.com": {"not an address"}} },
+			"no servers":     func(c *Config) { c.DNS.Servers = nil },
+		} {
+			config := NewConfig("info")
+			modify(config)
+			if err := config.Validate(); err == nil {
+				t.Errorf("Expected validation error for invalid %s", name)
+			}
+		}
+	})
+
+	t.Run("FakeDNS", func(t *testing.T) {
+		config := NewConfig("info")
+		config.EnableFakeDns("198.18.0.0/15", 65535)
+		config.EnableFakeDns("fc00::/18", 65535)
+		if config.DNS.Servers[0].Address != "fakedns" || len(config.DNS.Servers) != 4 {
+			t.Errorf("Expected the fakedns server to be added once and first, got %v", config.DNS.Servers)
+		}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+
+		config.FakeDNS[0].PoolSize = 1 << 20
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for a pool size larger than the pool")
+		}
+		config.FakeDNS[0].IPPool = "198.18.0.0"
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for an invalid pool")
+		}
+	})
+
+	t.Run("Sniffing Without FakeDNS", func(t *testing.T) {
+		config := NewConfig("info")
+		inbound := config.MakeVlessInbound("vless", 443, "b831381d-6324-4d53-ad4f-8cda48b30811", "tcp", nil)
+		inbound.Sniffing = config.MakeSniffing("fakedns+others")
+		config.Inbounds = append(config.Inbounds, inbound)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for fakedns sniffing without fakedns")
+		}
+
+		config.DNS.Servers = append(config.DNS.Servers, config.MakeDnsServer("fakedns", 0, nil, nil))
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error with the fakedns server: %v", err)
+		}
+	})
+}
+
+func TestDnsJson(t *testing.T) {
+	content := `{"servers":["8.8.8.8",{"address":"1.1.1.1","port":53,"domains":["geosite:ir"],"tag":"dns-out"}],` +
+		`"hosts":{"a.example":"10.0.0.1","b.example":["10.0.0.2","10.0.0.3"]}}`
+	var dns DNS
+	if err := json.Unmarshal([]byte(content), &dns); err != nil {
+		t.Fatal(err)
+	}
+	if dns.Servers[0].Address != "8.8.8.8" || dns.Servers[1].Port != 53 || string(dns.Servers[1].Extra["tag"]) != `"dns-out"` {
+		t.Errorf("Unexpected servers %+v %+v", dns.Servers[0], dns.Servers[1])
+	}
+	if !slices.Equal(dns.Hosts["a.example"], []string{"10.0.0.1"}) || len(dns.Hosts["b.example"]) != 2 {
+		t.Errorf("Unexpected hosts %v", dns.Hosts)
+	}
+
+	output, err := json.Marshal(&dns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != content {
+		t.Errorf("Unexpected JSON %s", output)
+	}
+
+	var fakeDns FakeDNS
+	if err = json.Unmarshal([]byte(`{"ipPool":"198.18.0.0/16","poolSize":1000}`), &fakeDns); err != nil {
+		t.Fatal(err)
+	}
+	if output, _ = json.Marshal(fakeDns); string(output) != `[{"ipPool":"198.18.0.0/16","poolSize":1000}]` {
+		t.Errorf("Unexpected JSON %s", output)
+	}
+}
+
+func TestDnsGeoReferences(t *testing.T) {
+	config := NewConfig("info")
+	config.DNS.Servers = append(config.DNS.Servers, config.MakeDnsServer("223.5.5.5", 0, []string{"geosite:cn"}, []string{"geoip:cn"}))
+	config.DNS.Hosts = map[string]DNSHost{"geosite:category-ads-all": {"127.0.0.1"}}
+
+	sites, ips := config.GeoReferences()
+	if !slices.Equal(sites, []string{"cn", "category-ads-all"}) {
+		t.Errorf("Unexpected geosite categories %v", sites)
+	}
+	if !slices.Equal(ips, []string{"cn"}) {
+		t.Errorf("Unexpected geoip codes %v", ips)
+	}
+}
@@ -0,0 +1,716 @@
+package xray
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// The config types keep the JSON keys they do not model in their Extra field and write them back when marshaled,
+// so newer Xray features sent by the manager reach the core even before the node validates them.
+// Modeled keys given with an empty value are kept there as well, so a config is written back as it was received.
+// Each type implements json.Unmarshaler and json.Marshaler through an alias type (without these methods) and the helpers below.
+
+// knownKeys caches the lowercase JSON keys modeled by each type, encoding/json matches keys case-insensitively.
+var knownKeys sync.Map
+
+func modeledKeys(t reflect.Type) map[string]bool {
+	if keys, found := knownKeys.Load(t); found {
+		return keys.(map[string]bool)
+	}
+
+	keys := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		keys[strings.ToLower(name)] = true
+	}
+
+	knownKeys.Store(t, keys)
+	return keys
+}
+
+// unmarshalExtra decodes the content into v (a pointer to an alias type) and returns the keys v does not model.
+// Modeled keys given with an empty value that marshaling omits (e.g. "level": 0) are returned too, to write them back.
+func unmarshalExtra(content []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(content, v); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(content, &all); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	keys := modeledKeys(reflect.TypeOf(v).Elem())
+	var written map[string]bool
+	var extra map[string]json.RawMessage
+	for key, value := range all {
+		if keys[strings.ToLower(key)] {
+			if written == nil {
+				encoded, err := json.Marshal(v)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				if written, err = writtenKeys(encoded); err != nil {
+					return nil, errors.WithStack(err)
+				}
+			}
+			if written[strings.ToLower(key)] {
+				continue
+			}
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[key] = value
+	}
+	return extra, nil
+}
+
+// writtenKeys returns the lowercase keys of the encoded object.
+func writtenKeys(content []byte) (map[string]bool, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(object))
+	for key := range object {
+		keys[strings.ToLower(key)] = true
+	}
+	return keys, nil
+}
+
+// marshalExtra encodes v (an alias type) and appends the extra keys in sorted order after the modeled ones.
+// A modeled key kept in extra is skipped when its field has been set since and is already written.
+func marshalExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	content, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return content, errors.WithStack(err)
+	}
+
+	modeled := modeledKeys(reflect.TypeOf(v))
+	var written map[string]bool
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		if modeled[strings.ToLower(key)] {
+			if written == nil {
+				if written, err = writtenKeys(content); err != nil {
+					return nil, errors.WithStack(err)
+				}
+			}
+			if written[strings.ToLower(key)] {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return content, nil
+	}
+	sort.Strings(keys)
+
+	var buffer bytes.Buffer
+	buffer.Write(content[:len(content)-1])
+	for i, key := range keys {
+		if i > 0 || len(content) > 2 {
+			buffer.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(extra[key])
+	}
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
+}
+
+func (l *Log) UnmarshalJSON(content []byte) (err error) {
+	type alias Log
+	l.Extra, err = unmarshalExtra(content, (*alias)(l))
+	return err
+}
+
+func (l Log) MarshalJSON() ([]byte, error) {
+	type alias Log
+	return marshalExtra(alias(l), l.Extra)
+}
+
+func (c *Client) UnmarshalJSON(content []byte) (err error) {
+	type alias Client
+	c.Extra, err = unmarshalExtra(content, (*alias)(c))
+	return err
+}
+
+func (c Client) MarshalJSON() ([]byte, error) {
+	type alias Client
+	return marshalExtra(alias(c), c.Extra)
+}
+
+func (i *InboundSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias InboundSettings
+	i.Extra, err = unmarshalExtra(content, (*alias)(i))
+	return err
+}
+
+func (i InboundSettings) MarshalJSON() ([]byte, error) {
+	type alias InboundSettings
+	return marshalExtra(alias(i), i.Extra)
+}
+
+func (f *Fallback) UnmarshalJSON(content []byte) (err error) {
+	type alias Fallback
+	f.Extra, err = unmarshalExtra(content, (*alias)(f))
+	return err
+}
+
+func (f Fallback) MarshalJSON() ([]byte, error) {
+	type alias Fallback
+	return marshalExtra(alias(f), f.Extra)
+}
+
+func (i *Inbound) UnmarshalJSON(content []byte) (err error) {
+	type alias Inbound
+	i.Extra, err = unmarshalExtra(content, (*alias)(i))
+	return err
+}
+
+func (i Inbound) MarshalJSON() ([]byte, error) {
+	type alias Inbound
+	return marshalExtra(alias(i), i.Extra)
+}
+
+func (s *Sniffing) UnmarshalJSON(content []byte) (err error) {
+	type alias Sniffing
+	s.Extra, err = unmarshalExtra(content, (*alias)(s))
+	return err
+}
+
+func (s Sniffing) MarshalJSON() ([]byte, error) {
+	type alias Sniffing
+	return marshalExtra(alias(s), s.Extra)
+}
+
+func (o *OutboundServer) UnmarshalJSON(content []byte) (err error) {
+	type alias OutboundServer
+	o.Extra, err = unmarshalExtra(content, (*alias)(o))
+	return err
+}
+
+func (o OutboundServer) MarshalJSON() ([]byte, error) {
+	type alias OutboundServer
+	return marshalExtra(alias(o), o.Extra)
+}
+
+func (a *Account) UnmarshalJSON(content []byte) (err error) {
+	type alias Account
+	a.Extra, err = unmarshalExtra(content, (*alias)(a))
+	return err
+}
+
+func (a Account) MarshalJSON() ([]byte, error) {
+	type alias Account
+	return marshalExtra(alias(a), a.Extra)
+}
+
+func (o *OutboundSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias OutboundSettings
+	o.Extra, err = unmarshalExtra(content, (*alias)(o))
+	return err
+}
+
+func (o OutboundSettings) MarshalJSON() ([]byte, error) {
+	type alias OutboundSettings
+	return marshalExtra(alias(o), o.Extra)
+}
+
+func (f *Fragment) UnmarshalJSON(content []byte) (err error) {
+	type alias Fragment
+	f.Extra, err = unmarshalExtra(content, (*alias)(f))
+	return err
+}
+
+func (f Fragment) MarshalJSON() ([]byte, error) {
+	type alias Fragment
+	return marshalExtra(alias(f), f.Extra)
+}
+
+func (n *Noise) UnmarshalJSON(content []byte) (err error) {
+	type alias Noise
+	n.Extra, err = unmarshalExtra(content, (*alias)(n))
+	return err
+}
+
+func (n Noise) MarshalJSON() ([]byte, error) {
+	type alias Noise
+	return marshalExtra(alias(n), n.Extra)
+}
+
+func (b *BlackholeResponse) UnmarshalJSON(content []byte) (err error) {
+	type alias BlackholeResponse
+	b.Extra, err = unmarshalExtra(content, (*alias)(b))
+	return err
+}
+
+func (b BlackholeResponse) MarshalJSON() ([]byte, error) {
+	type alias BlackholeResponse
+	return marshalExtra(alias(b), b.Extra)
+}
+
+func (v *VnextServer) UnmarshalJSON(content []byte) (err error) {
+	type alias VnextServer
+	v.Extra, err = unmarshalExtra(content, (*alias)(v))
+	return err
+}
+
+func (v VnextServer) MarshalJSON() ([]byte, error) {
+	type alias VnextServer
+	return marshalExtra(alias(v), v.Extra)
+}
+
+func (v *VmessUser) UnmarshalJSON(content []byte) (err error) {
+	type alias VmessUser
+	v.Extra, err = unmarshalExtra(content, (*alias)(v))
+	return err
+}
+
+func (v VmessUser) MarshalJSON() ([]byte, error) {
+	type alias VmessUser
+	return marshalExtra(alias(v), v.Extra)
+}
+
+func (s *StreamSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias StreamSettings
+	s.Extra, err = unmarshalExtra(content, (*alias)(s))
+	return err
+}
+
+func (s StreamSettings) MarshalJSON() ([]byte, error) {
+	type alias StreamSettings
+	return marshalExtra(alias(s), s.Extra)
+}
+
+func (t *TcpSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias TcpSettings
+	t.Extra, err = unmarshalExtra(content, (*alias)(t))
+	return err
+}
+
+func (t TcpSettings) MarshalJSON() ([]byte, error) {
+	type alias TcpSettings
+	return marshalExtra(alias(t), t.Extra)
+}
+
+func (t *TcpHeaderObject) UnmarshalJSON(content []byte) (err error) {
+	type alias TcpHeaderObject
+	t.Extra, err = unmarshalExtra(content, (*alias)(t))
+	return err
+}
+
+func (t TcpHeaderObject) MarshalJSON() ([]byte, error) {
+	type alias TcpHeaderObject
+	return marshalExtra(alias(t), t.Extra)
+}
+
+func (h *HttpRequestObject) UnmarshalJSON(content []byte) (err error) {
+	type alias HttpRequestObject
+	h.Extra, err = unmarshalExtra(content, (*alias)(h))
+	return err
+}
+
+func (h HttpRequestObject) MarshalJSON() ([]byte, error) {
+	type alias HttpRequestObject
+	return marshalExtra(alias(h), h.Extra)
+}
+
+func (h *HttpResponseObject) UnmarshalJSON(content []byte) (err error) {
+	type alias HttpResponseObject
+	h.Extra, err = unmarshalExtra(content, (*alias)(h))
+	return err
+}
+
+func (h HttpResponseObject) MarshalJSON() ([]byte, error) {
+	type alias HttpResponseObject
+	return marshalExtra(alias(h), h.Extra)
+}
+
+func (w *WebSocketSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias WebSocketSettings
+	w.Extra, err = unmarshalExtra(content, (*alias)(w))
+	return err
+}
+
+func (w WebSocketSettings) MarshalJSON() ([]byte, error) {
+	type alias WebSocketSettings
+	return marshalExtra(alias(w), w.Extra)
+}
+
+func (h *HttpSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias HttpSettings
+	h.Extra, err = unmarshalExtra(content, (*alias)(h))
+	return err
+}
+
+func (h HttpSettings) MarshalJSON() ([]byte, error) {
+	type alias HttpSettings
+	return marshalExtra(alias(h), h.Extra)
+}
+
+func (g *GrpcSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias GrpcSettings
+	g.Extra, err = unmarshalExtra(content, (*alias)(g))
+	return err
+}
+
+func (g GrpcSettings) MarshalJSON() ([]byte, error) {
+	type alias GrpcSettings
+	return marshalExtra(alias(g), g.Extra)
+}
+
+func (k *KcpSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias KcpSettings
+	k.Extra, err = unmarshalExtra(content, (*alias)(k))
+	return err
+}
+
+func (k KcpSettings) MarshalJSON() ([]byte, error) {
+	type alias KcpSettings
+	return marshalExtra(alias(k), k.Extra)
+}
+
+func (k *KcpHeaderObject) UnmarshalJSON(content []byte) (err error) {
+	type alias KcpHeaderObject
+	k.Extra, err = unmarshalExtra(content, (*alias)(k))
+	return err
+}
+
+func (k KcpHeaderObject) MarshalJSON() ([]byte, error) {
+	type alias KcpHeaderObject
+	return marshalExtra(alias(k), k.Extra)
+}
+
+func (h *HttpUpgradeSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias HttpUpgradeSettings
+	h.Extra, err = unmarshalExtra(content, (*alias)(h))
+	return err
+}
+
+func (h HttpUpgradeSettings) MarshalJSON() ([]byte, error) {
+	type alias HttpUpgradeSettings
+	return marshalExtra(alias(h), h.Extra)
+}
+
+func (x *XhttpSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias XhttpSettings
+	x.Extra, err = unmarshalExtra(content, (*alias)(x))
+	return err
+}
+
+func (x XhttpSettings) MarshalJSON() ([]byte, error) {
+	type alias XhttpSettings
+	return marshalExtra(alias(x), x.Extra)
+}
+
+func (s *SocketSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias SocketSettings
+	s.Extra, err = unmarshalExtra(content, (*alias)(s))
+	return err
+}
+
+func (s SocketSettings) MarshalJSON() ([]byte, error) {
+	type alias SocketSettings
+	return marshalExtra(alias(s), s.Extra)
+}
+
+func (t *TlsSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias TlsSettings
+	t.Extra, err = unmarshalExtra(content, (*alias)(t))
+	return err
+}
+
+func (t TlsSettings) MarshalJSON() ([]byte, error) {
+	type alias TlsSettings
+	return marshalExtra(alias(t), t.Extra)
+}
+
+func (r *RealitySettings) UnmarshalJSON(content []byte) (err error) {
+	type alias RealitySettings
+	r.Extra, err = unmarshalExtra(content, (*alias)(r))
+	return err
+}
+
+func (r RealitySettings) MarshalJSON() ([]byte, error) {
+	type alias RealitySettings
+	return marshalExtra(alias(r), r.Extra)
+}
+
+func (o *Outbound) UnmarshalJSON(content []byte) (err error) {
+	type alias Outbound
+	o.Extra, err = unmarshalExtra(content, (*alias)(o))
+	return err
+}
+
+func (o Outbound) MarshalJSON() ([]byte, error) {
+	type alias Outbound
+	return marshalExtra(alias(o), o.Extra)
+}
+
+func (p *ProxySettings) UnmarshalJSON(content []byte) (err error) {
+	type alias ProxySettings
+	p.Extra, err = unmarshalExtra(content, (*alias)(p))
+	return err
+}
+
+func (p ProxySettings) MarshalJSON() ([]byte, error) {
+	type alias ProxySettings
+	return marshalExtra(alias(p), p.Extra)
+}
+
+func (m *Mux) UnmarshalJSON(content []byte) (err error) {
+	type alias Mux
+	m.Extra, err = unmarshalExtra(content, (*alias)(m))
+	return err
+}
+
+func (m Mux) MarshalJSON() ([]byte, error) {
+	type alias Mux
+	return marshalExtra(alias(m), m.Extra)
+}
+
+func (d *DNS) UnmarshalJSON(content []byte) (err error) {
+	type alias DNS
+	d.Extra, err = unmarshalExtra(content, (*alias)(d))
+	return err
+}
+
+func (d DNS) MarshalJSON() ([]byte, error) {
+	type alias DNS
+	return marshalExtra(alias(d), d.Extra)
+}
+
+func (f *FakeDNSPool) UnmarshalJSON(content []byte) (err error) {
+	type alias FakeDNSPool
+	f.Extra, err = unmarshalExtra(content, (*alias)(f))
+	return err
+}
+
+func (f FakeDNSPool) MarshalJSON() ([]byte, error) {
+	type alias FakeDNSPool
+	return marshalExtra(alias(f), f.Extra)
+}
+
+func (a *API) UnmarshalJSON(content []byte) (err error) {
+	type alias API
+	a.Extra, err = unmarshalExtra(content, (*alias)(a))
+	return err
+}
+
+func (a API) MarshalJSON() ([]byte, error) {
+	type alias API
+	return marshalExtra(alias(a), a.Extra)
+}
+
+func (p *PolicyLevels) UnmarshalJSON(content []byte) (err error) {
+	type alias PolicyLevels
+	p.Extra, err = unmarshalExtra(content, (*alias)(p))
+	return err
+}
+
+func (p PolicyLevels) MarshalJSON() ([]byte, error) {
+	type alias PolicyLevels
+	return marshalExtra(alias(p), p.Extra)
+}
+
+func (p *PolicySystem) UnmarshalJSON(content []byte) (err error) {
+	type alias PolicySystem
+	p.Extra, err = unmarshalExtra(content, (*alias)(p))
+	return err
+}
+
+func (p PolicySystem) MarshalJSON() ([]byte, error) {
+	type alias PolicySystem
+	return marshalExtra(alias(p), p.Extra)
+}
+
+func (p *Policy) UnmarshalJSON(content []byte) (err error) {
+	type alias Policy
+	p.Extra, err = unmarshalExtra(content, (*alias)(p))
+	return err
+}
+
+func (p Policy) MarshalJSON() ([]byte, error) {
+	type alias Policy
+	return marshalExtra(alias(p), p.Extra)
+}
+
+func (r *Rule) UnmarshalJSON(content []byte) (err error) {
+	type alias Rule
+	r.Extra, err = unmarshalExtra(content, (*alias)(r))
+	return err
+}
+
+func (r Rule) MarshalJSON() ([]byte, error) {
+	type alias Rule
+	return marshalExtra(alias(r), r.Extra)
+}
+
+func (r *RoutingSettings) UnmarshalJSON(content []byte) (err error) {
+	type alias RoutingSettings
+	r.Extra, err = unmarshalExtra(content, (*alias)(r))
+	return err
+}
+
+func (r RoutingSettings) MarshalJSON() ([]byte, error) {
+	type alias RoutingSettings
+	return marshalExtra(alias(r), r.Extra)
+}
+
+func (b *Balancer) UnmarshalJSON(content []byte) (err error) {
+	type alias Balancer
+	b.Extra, err = unmarshalExtra(content, (*alias)(b))
+	return err
+}
+
+func (b Balancer) MarshalJSON() ([]byte, error) {
+	type alias Balancer
+	return marshalExtra(alias(b), b.Extra)
+}
+
+func (b *BalancerStrategy) UnmarshalJSON(content []byte) (err error) {
+	type alias BalancerStrategy
+	b.Extra, err = unmarshalExtra(content, (*alias)(b))
+	return err
+}
+
+func (b BalancerStrategy) MarshalJSON() ([]byte, error) {
+	type alias BalancerStrategy
+	return marshalExtra(alias(b), b.Extra)
+}
+
+func (b *BalancerStrategySettings) UnmarshalJSON(content []byte) (err error) {
+	type alias BalancerStrategySettings
+	b.Extra, err = unmarshalExtra(content, (*alias)(b))
+	return err
+}
+
+func (b BalancerStrategySettings) MarshalJSON() ([]byte, error) {
+	type alias BalancerStrategySettings
+	return marshalExtra(alias(b), b.Extra)
+}
+
+func (b *BalancerWeight) UnmarshalJSON(content []byte) (err error) {
+	type alias BalancerWeight
+	b.Extra, err = unmarshalExtra(content, (*alias)(b))
+	return err
+}
+
+func (b BalancerWeight) MarshalJSON() ([]byte, error) {
+	type alias BalancerWeight
+	return marshalExtra(alias(b), b.Extra)
+}
+
+func (o *Observatory) UnmarshalJSON(content []byte) (err error) {
+	type alias Observatory
+	o.Extra, err = unmarshalExtra(content, (*alias)(o))
+	return err
+}
+
+func (o Observatory) MarshalJSON() ([]byte, error) {
+	type alias Observatory
+	return marshalExtra(alias(o), o.Extra)
+}
+
+func (b *BurstObservatory) UnmarshalJSON(content []byte) (err error) {
+	type alias BurstObservatory
+	b.Extra, err = unmarshalExtra(content, (*alias)(b))
+	return err
+}
+
+func (b BurstObservatory) MarshalJSON() ([]byte, error) {
+	type alias BurstObservatory
+	return marshalExtra(alias(b), b.Extra)
+}
+
+func (p *PingConfig) UnmarshalJSON(content []byte) (err error) {
+	type alias PingConfig
+	p.Extra, err = unmarshalExtra(content, (*alias)(p))
+	return err
+}
+
+func (p PingConfig) MarshalJSON() ([]byte, error) {
+	type alias PingConfig
+	return marshalExtra(alias(p), p.Extra)
+}
+
+func (r *Routing) UnmarshalJSON(content []byte) (err error) {
+	type alias Routing
+	r.Extra, err = unmarshalExtra(content, (*alias)(r))
+	return err
+}
+
+func (r Routing) MarshalJSON() ([]byte, error) {
+	type alias Routing
+	return marshalExtra(alias(r), r.Extra)
+}
+
+func (r *Reverse) UnmarshalJSON(content []byte) (err error) {
+	type alias Reverse
+	r.Extra, err = unmarshalExtra(content, (*alias)(r))
+	return err
+}
+
+func (r Reverse) MarshalJSON() ([]byte, error) {
+	type alias Reverse
+	return marshalExtra(alias(r), r.Extra)
+}
+
+func (r *ReverseItem) UnmarshalJSON(content []byte) (err error) {
+	type alias ReverseItem
+	r.Extra, err = unmarshalExtra(content, (*alias)(r))
+	return err
+}
+
+func (r ReverseItem) MarshalJSON() ([]byte, error) {
+	type alias ReverseItem
+	return marshalExtra(alias(r), r.Extra)
+}
+
+func (m *Metadata) UnmarshalJSON(content []byte) (err error) {
+	type alias Metadata
+	m.Extra, err = unmarshalExtra(content, (*alias)(m))
+	return err
+}
+
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	type alias Metadata
+	return marshalExtra(alias(m), m.Extra)
+}
+
+func (c *Config) UnmarshalJSON(content []byte) (err error) {
+	type alias Config
+	c.Extra, err = unmarshalExtra(content, (*alias)(c))
+	return err
+}
+
+func (c Config) MarshalJSON() ([]byte, error) {
+	type alias Config
+	return marshalExtra(alias(c), c.Extra)
+}
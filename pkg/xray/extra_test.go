@@ -0,0 +1,166 @@
+package xray
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// canonicalize re-encodes the JSON content with sorted object keys and without insignificant whitespace.
+func canonicalize(t *testing.T, content []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("Cannot decode: %v", err)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Cannot encode: %v", err)
+	}
+	return canonical
+}
+
+// TestUnknownFieldsSemanticRoundTrip checks that the configs are semantically equal after a round trip,
+// the key order and whitespace are not kept (modeled keys follow the struct fields, extra keys are sorted).
+func TestUnknownFieldsSemanticRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No test configs found: %v", err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var c Config
+			if err = json.Unmarshal(content, &c); err != nil {
+				t.Fatalf("Cannot unmarshal: %v", err)
+			}
+			if err = c.Validate(); err != nil {
+				t.Fatalf("Unexpected validation error: %v", err)
+			}
+			canonical, err := json.Marshal(&c)
+			if err != nil {
+				t.Fatalf("Cannot marshal: %v", err)
+			}
+
+			if want, got := canonicalize(t, content), canonicalize(t, canonical); !bytes.Equal(want, got) {
+				t.Errorf("Round trip is not semantically equal:\n%s\n%s", want, got)
+			}
+
+			var again Config
+			if err = json.Unmarshal(canonical, &again); err != nil {
+				t.Fatalf("Cannot unmarshal the canonical form: %v", err)
+			}
+			second, err := json.Marshal(&again)
+			if err != nil {
+				t.Fatalf("Cannot marshal: %v", err)
+			}
+			if !bytes.Equal(canonical, second) {
+				t.Errorf("Round trip is not idempotent:\n%s\n%s", canonical, second)
+			}
+		})
+	}
+}
+
+func TestUnknownFieldsBuilders(t *testing.T) {
+	c := NewConfig("info")
+	inbound := c.MakeVlessInbound("vless", 443, "b831381d-6324-4d53-ad4f-8cda48b30811", "tcp", nil)
+	inbound.Extra = map[string]json.RawMessage{"allocate": json.RawMessage(`{"strategy":"always"}`)}
+	c.Inbounds = append(c.Inbounds, inbound)
+
+	content, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Config
+	if err = json.Unmarshal(content, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded.FindInbound("vless").Extra["allocate"]) != `{"strategy":"always"}` {
+		t.Errorf("Expected allocate to be kept, got %v", decoded.FindInbound("vless").Extra)
+	}
+	if !c.Equals(&decoded) {
+		t.Error("Expected the decoded config to equal the original")
+	}
+}
+
+// extraTypes returns the struct types reachable from t that have an Extra field.
+func extraTypes(t reflect.Type, seen map[reflect.Type]bool) []reflect.Type {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return extraTypes(t.Elem(), seen)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	var types []reflect.Type
+	if _, found := t.FieldByName("Extra"); found {
+		types = append(types, t)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			types = append(types, extraTypes(t.Field(i).Type, seen)...)
+		}
+	}
+	return types
+}
+
+func TestUnknownFieldsMethods(t *testing.T) {
+	types := extraTypes(reflect.TypeOf(Config{}), map[reflect.Type]bool{})
+	if len(types) < 2 {
+		t.Fatalf("Expected the config types with an Extra field, got %v", types)
+	}
+
+	marshaler := reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	unmarshaler := reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	for _, typ := range types {
+		t.Run(typ.Name(), func(t *testing.T) {
+			if !typ.Implements(marshaler) || !reflect.PointerTo(typ).Implements(unmarshaler) {
+				t.Fatalf("%s has an Extra field without its MarshalJSON and UnmarshalJSON methods", typ)
+			}
+
+			v := reflect.New(typ)
+			v.Elem().FieldByName("Extra").Set(reflect.ValueOf(map[string]json.RawMessage{"unknownKey": json.RawMessage(`1`)}))
+			content, err := json.Marshal(v.Interface())
+			if err != nil {
+				t.Fatalf("Cannot marshal: %v", err)
+			}
+			decoded := reflect.New(typ)
+			if err = json.Unmarshal(content, decoded.Interface()); err != nil {
+				t.Fatalf("Cannot unmarshal %s: %v", content, err)
+			}
+			extra := decoded.Elem().FieldByName("Extra").Interface().(map[string]json.RawMessage)
+			if string(extra["unknownKey"]) != `1` {
+				t.Errorf("Expected the unknown key to be kept, got %s", content)
+			}
+		})
+	}
+}
+
+func TestUnknownFieldsEmptyValues(t *testing.T) {
+	var client Client
+	if err := json.Unmarshal([]byte(`{"email":"alice@example.com","level":0,"flow":""}`), &client); err != nil {
+		t.Fatal(err)
+	}
+	if output, _ := json.Marshal(client); string(output) != `{"email":"alice@example.com","flow":"","level":0}` {
+		t.Errorf("Expected the empty values to be kept, got %s", output)
+	}
+
+	client.Level = 1
+	if output, _ := json.Marshal(client); string(output) != `{"email":"alice@example.com","level":1,"flow":""}` {
+		t.Errorf("Expected the level to be written once, got %s", output)
+	}
+}
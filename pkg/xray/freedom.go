@@ -0,0 +1,192 @@
+package xray
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// freedomDomainStrategies are the ways freedom resolves domains, "Force" strategies fail when no address of the family is found.
+var freedomDomainStrategies = []string{
+	"AsIs", "UseIP", "UseIPv4", "UseIPv6", "UseIPv4v6", "UseIPv6v4",
+	"ForceIP", "ForceIPv4", "ForceIPv6", "ForceIPv4v6", "ForceIPv6v4",
+}
+
+// IntRange is a number or a "min-max" range, e.g. "100-200".
+// Xray accepts a single number as a JSON number or string, it is written as a number.
+type IntRange string
+
+func (r *IntRange) UnmarshalJSON(content []byte) error {
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		*r = IntRange(s)
+		return nil
+	}
+	var value json.Number
+	if err := json.Unmarshal(content, &value); err != nil {
+		return errors.New("range must be a number or a string")
+	}
+	*r = IntRange(value.String())
+	return nil
+}
+
+func (r IntRange) MarshalJSON() ([]byte, error) {
+	if value, err := strconv.Atoi(string(r)); err == nil {
+		return json.Marshal(value)
+	}
+	return json.Marshal(string(r))
+}
+
+// Bounds returns the minimum and maximum of the range, which must not be negative.
+func (r IntRange) Bounds() (from, to int, err error) {
+	first, last, isRange := strings.Cut(string(r), "-")
+	if !isRange {
+		last = first
+	}
+	from, err1 := strconv.Atoi(strings.TrimSpace(first))
+	to, err2 := strconv.Atoi(strings.TrimSpace(last))
+	if err1 != nil || err2 != nil || from < 0 || from > to {
+		return 0, 0, errors.Errorf("invalid range '%s'", r)
+	}
+	return from, to, nil
+}
+
+// Fragment splits the first packets of the connections, packets is "tlshello" (the TLS Client Hello), a range of packets
+// (e.g. "1-3") or empty for all of them. The length of the fragments is in bytes and the interval between them in milliseconds.
+type Fragment struct {
+	Packets  string   `json:"packets,omitempty"`
+	Length   IntRange `json:"length" validate:"required"`
+	Interval IntRange `json:"interval" validate:"required"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Noise is a packet sent before the UDP traffic: "rand" with a length range, or "str", "hex" and "base64" with its content.
+// The delay after it is in milliseconds.
+type Noise struct {
+	Type   string   `json:"type" validate:"required,oneof=rand str hex base64"`
+	Packet string   `json:"packet" validate:"required"`
+	Delay  IntRange `json:"delay,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// BlackholeResponse is what blackhole sends before closing the connection, "none" or an HTTP 403 response.
+type BlackholeResponse struct {
+	Type string `json:"type" validate:"required,oneof=none http"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// MakeFreedomOutbound creates a freedom outbound resolving domains with the given strategy (e.g. "UseIPv4" on dual-stack nodes).
+func (c *Config) MakeFreedomOutbound(tag, domainStrategy string) *Outbound {
+	return &Outbound{
+		Tag:      tag,
+		Protocol: "freedom",
+		Settings: &OutboundSettings{
+			DomainStrategy: domainStrategy,
+		},
+	}
+}
+
+// MakeBlackholeOutbound creates a blackhole outbound sending the given response type ("none" or "http").
+func (c *Config) MakeBlackholeOutbound(tag, responseType string) *Outbound {
+	return &Outbound{
+		Tag:      tag,
+		Protocol: "blackhole",
+		Settings: &OutboundSettings{
+			Response: &BlackholeResponse{Type: responseType},
+		},
+	}
+}
+
+// MakeFragment creates fragment settings, e.g. MakeFragment("tlshello", "100-200", "10-20") splits the TLS Client Hello.
+func (c *Config) MakeFragment(packets, length, interval string) *Fragment {
+	return &Fragment{
+		Packets:  packets,
+		Length:   IntRange(length),
+		Interval: IntRange(interval),
+	}
+}
+
+// validateOutboundSettings checks the settings of the settings-only protocols.
+// The blackhole response type is checked by its struct tag.
+func validateOutboundSettings(settings *OutboundSettings, protocol string) error {
+	switch protocol {
+	case "freedom":
+		return validateFreedom(settings)
+	}
+	return nil
+}
+
+// validateFreedom checks the domain strategy, the redirect "address:port", the fragment ranges and the noise packets.
+func validateFreedom(settings *OutboundSettings) error {
+	if settings.DomainStrategy != "" && !slices.Contains(freedomDomainStrategies, settings.DomainStrategy) {
+		return errors.Errorf("invalid domainStrategy '%s'", settings.DomainStrategy)
+	}
+
+	if settings.Redirect != "" {
+		host, port, err := net.SplitHostPort(settings.Redirect)
+		if err != nil {
+			return errors.Errorf("invalid redirect '%s', expected address:port", settings.Redirect)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return errors.Errorf("invalid redirect port '%s'", port)
+		}
+		if host != "" && net.ParseIP(host) == nil && !validHostname(host) {
+			return errors.Errorf("invalid redirect address '%s'", host)
+		}
+	}
+
+	if f := settings.Fragment; f != nil {
+		if f.Packets != "tlshello" && f.Packets != "" {
+			if from, _, err := IntRange(f.Packets).Bounds(); err != nil || from == 0 {
+				return errors.Errorf("invalid fragment packets '%s', expected tlshello or a range from 1", f.Packets)
+			}
+		}
+		if from, _, err := f.Length.Bounds(); err != nil || from == 0 {
+			return errors.Errorf("invalid fragment length '%s', expected a range from 1", f.Length)
+		}
+		if _, _, err := f.Interval.Bounds(); err != nil {
+			return errors.Wrap(err, "invalid fragment interval")
+		}
+	}
+
+	for _, n := range settings.Noises {
+		if err := validateNoise(n); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func validateNoise(n *Noise) error {
+	packet := strings.TrimSpace(n.Packet)
+	switch n.Type {
+	case "rand":
+		if from, _, err := IntRange(packet).Bounds(); err != nil || from == 0 {
+			return errors.Errorf("invalid rand noise length '%s', expected a range from 1", n.Packet)
+		}
+	case "hex":
+		if _, err := hex.DecodeString(packet); err != nil {
+			return errors.Errorf("invalid hex noise '%s'", n.Packet)
+		}
+	case "base64":
+		normalized := strings.NewReplacer("+", "-", "/", "_", "=", "").Replace(packet)
+		if _, err := base64.RawURLEncoding.DecodeString(normalized); err != nil {
+			return errors.Errorf("invalid base64 noise '%s'", n.Packet)
+		}
+	}
+	if n.Delay != "" {
+		if _, _, err := n.Delay.Bounds(); err != nil {
+			return errors.Wrap(err, "invalid noise delay")
+		}
+	}
+	return nil
+}
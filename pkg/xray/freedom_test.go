@@ -0,0 +1,84 @@
+package xray
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFreedomValidation(t *testing.T) {
+	t.Run("Settings", func(t *testing.T) {
+		config := NewConfig("info")
+		direct := config.MakeFreedomOutbound("direct", "UseIPv4")
+		direct.Settings.Fragment = config.MakeFragment("tlshello", "100-200", "10-20")
+		direct.Settings.Noises = []*Noise{
+			{Type: "rand", Packet: "10-20", Delay: "10-16"},
+			{Type: "str", Packet: "hello"},
+			{Type: "hex", Packet: "7468697320"},
+			{Type: "base64", Packet: "dGhpcyBpcw=="},
+		}
+		direct.Settings.ProxyProtocol = 2
+		redirect := config.MakeFreedomOutbound("redirect", "")
+		redirect.Settings.Redirect = "127.0.0.1:8080"
+		config.Outbounds = append(config.Outbounds, direct, redirect, config.MakeBlackholeOutbound("block", "http"))
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for name, modify := range map[string]func(s *OutboundSettings){
+			"domain strategy":  func(s *OutboundSettings) { s.DomainStrategy = "PreferIPv4" },
+			"redirect":         func(s *OutboundSettings) { s.Redirect = "127.0.0.1" },
+			"redirect port":    func(s *OutboundSettings) { s.Redirect = "127.0.0.1:0" },
+			"fragment packets": func(s *OutboundSettings) { s.Fragment = &Fragment{Packets: "0-3", Length: "100", Interval: "10"} },
+			"fragment length":  func(s *OutboundSettings) { s.Fragment = &Fragment{Packets: "1-3", Length: "0-100", Interval: "10"} },
+			"fragment interval": func(s *OutboundSettings) {
+				s.Fragment = &Fragment{Packets: "tlshello", Length: "100", Interval: "20-10"}
+			},
+			"noise type":     func(s *OutboundSettings) { s.Noises = []*Noise{{Type: "random", Packet: "10"}} },
+			"noise hex":      func(s *OutboundSettings) { s.Noises = []*Noise{{Type: "hex", Packet: "xyz"}} },
+			"noise rand":     func(s *OutboundSettings) { s.Noises = []*Noise{{Type: "rand", Packet: "0-10"}} },
+			"noise delay":    func(s *OutboundSettings) { s.Noises = []*Noise{{Type: "str", Packet: "a", Delay: "soon"}} },
+			"proxy protocol": func(s *OutboundSettings) { s.ProxyProtocol = 3 },
+		} {
+			config := NewConfig("info")
+			direct := config.MakeFreedomOutbound("direct", "")
+			modify(direct.Settings)
+			config.Outbounds = append(config.Outbounds, direct)
+			if err := config.Validate(); err == nil {
+				t.Errorf("Expected validation error for invalid %s", name)
+			}
+		}
+	})
+
+	t.Run("Blackhole", func(t *testing.T) {
+		config := NewConfig("info")
+		config.Outbounds = append(config.Outbounds, config.MakeBlackholeOutbound("block", "teapot"))
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for an unknown response type")
+		}
+
+		config.Outbounds[1] = config.MakeBlackholeOutbound("block", "none")
+		config.Outbounds[1].Settings.Servers = []*OutboundServer{{Address: "example.com", Port: 80}}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for servers on blackhole")
+		}
+	})
+}
+
+func TestFragmentJson(t *testing.T) {
+	var fragment Fragment
+	if err := json.Unmarshal([]byte(`{"packets":"tlshello","length":100,"interval":"10-20"}`), &fragment); err != nil {
+		t.Fatal(err)
+	}
+	if fragment.Length != "100" || fragment.Interval != "10-20" {
+		t.Errorf("Unexpected fragment %+v", fragment)
+	}
+	output, err := json.Marshal(fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != `{"packets":"tlshello","length":100,"interval":"10-20"}` {
+		t.Errorf("Unexpected JSON %s", output)
+	}
+}
@@ -0,0 +1,171 @@
+package xray
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	observatory "github.com/xtls/xray-core/app/observatory/command"
+)
+
+const ObservatoryService = "ObservatoryService"
+
+// OutboundHealth is the latest observatory result of an outbound.
+type OutboundHealth struct {
+	Tag          string `json:"tag"`
+	Alive        bool   `json:"alive"`
+	Delay        int64  `json:"delay"`
+	LastError    string `json:"last_error,omitempty"`
+	LastSeenTime int64  `json:"last_seen_time,omitempty"`
+	LastTryTime  int64  `json:"last_try_time,omitempty"`
+}
+
+// MatchSelector returns the outbound tags matching the given selectors, Xray matches selectors as tag prefixes.
+func (c *Config) MatchSelector(selectors []string) []string {
+	var tags []string
+	for _, o := range c.Outbounds {
+		for _, s := range selectors {
+			if strings.HasPrefix(o.Tag, s) {
+				tags = append(tags, o.Tag)
+				break
+			}
+		}
+	}
+	return tags
+}
+
+// EnableObservatory adds an observatory probing the selected outbounds and exposes its results through the API.
+func (c *Config) EnableObservatory(selectors []string, probeUrl, interval string) *Observatory {
+	c.Observatory = &Observatory{
+		SubjectSelector: selectors,
+		ProbeUrl:        probeUrl,
+		ProbeInterval:   interval,
+	}
+	c.EnableApiService(ObservatoryService)
+	return c.Observatory
+}
+
+// EnableBurstObservatory adds a burst observatory probing the selected outbounds and exposes its results through the API.
+func (c *Config) EnableBurstObservatory(selectors []string, destination, interval string, sampling int) *BurstObservatory {
+	c.BurstObservatory = &BurstObservatory{
+		SubjectSelector: selectors,
+		PingConfig: &PingConfig{
+			Destination: destination,
+			Interval:    interval,
+			Sampling:    sampling,
+		},
+	}
+	c.EnableApiService(ObservatoryService)
+	return c.BurstObservatory
+}
+
+// EnableApiService adds the given gRPC service to the API services if it is missing.
+func (c *Config) EnableApiService(service string) {
+	if !slices.Contains(c.API.Services, service) {
+		c.API.Services = append(c.API.Services, service)
+	}
+}
+
+// enableRequiredServices adds the API services the config depends on, the observatory service for the outbound health.
+func (c *Config) enableRequiredServices() {
+	if c.API != nil && (c.Observatory != nil || c.BurstObservatory != nil) {
+		c.EnableApiService(ObservatoryService)
+	}
+}
+
+// validateBalancers checks balancer references and that each strategy has the observatory it depends on.
+func (c *Config) validateBalancers() error {
+	if d := c.invalidObservatoryDuration(); d != "" {
+		return errors.Errorf("observatory has invalid duration '%s'", d)
+	}
+
+	if c.Routing == nil {
+		return nil
+	}
+
+	for _, b := range c.Routing.Balancers {
+		if len(c.MatchSelector(b.Selector)) == 0 {
+			return errors.Errorf("balancer %s selects no outbound", b.Tag)
+		}
+		if b.FallbackTag != "" && c.FindOutbound(b.FallbackTag) == nil {
+			return errors.Errorf("balancer %s fallback outbound %s not found", b.Tag, b.FallbackTag)
+		}
+		if b.Strategy == nil {
+			continue
+		}
+		switch b.Strategy.Type {
+		case "leastPing":
+			if c.Observatory == nil {
+				return errors.Errorf("balancer %s uses leastPing which requires observatory", b.Tag)
+			}
+		case "leastLoad":
+			if c.BurstObservatory == nil {
+				return errors.Errorf("balancer %s uses leastLoad which requires burstObservatory", b.Tag)
+			}
+		}
+		if s := b.Strategy.Settings; s != nil && !validDuration(s.MaxRTT) {
+			return errors.Errorf("balancer %s has invalid maxRTT '%s'", b.Tag, s.MaxRTT)
+		}
+	}
+
+	for _, rule := range c.Routing.Rules {
+		if rule.BalancerTag != "" && c.FindBalancer(rule.BalancerTag) == nil {
+			return errors.Errorf("rule balancer %s not found", rule.BalancerTag)
+		}
+	}
+
+	return nil
+}
+
+// invalidObservatoryDuration returns the first invalid duration in the observatory configs, or an empty string.
+func (c *Config) invalidObservatoryDuration() string {
+	var durations []string
+	if c.Observatory != nil {
+		durations = append(durations, c.Observatory.ProbeInterval)
+	}
+	if c.BurstObservatory != nil && c.BurstObservatory.PingConfig != nil {
+		durations = append(durations, c.BurstObservatory.PingConfig.Interval, c.BurstObservatory.PingConfig.Timeout)
+	}
+	for _, d := range durations {
+		if !validDuration(d) {
+			return d
+		}
+	}
+	return ""
+}
+
+func validDuration(d string) bool {
+	if d == "" {
+		return true
+	}
+	_, err := time.ParseDuration(d)
+	return err == nil
+}
+
+// QueryObservatory returns the health of the outbounds observed by the running core.
+func (x *Xray) QueryObservatory() ([]*OutboundHealth, error) {
+	connection, err := x.apiConnection()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	client := observatory.NewObservatoryServiceClient(connection)
+	r, err := client.GetOutboundStatus(context.Background(), &observatory.GetOutboundStatusRequest{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	result := make([]*OutboundHealth, 0, len(r.GetStatus().GetStatus()))
+	for _, s := range r.GetStatus().GetStatus() {
+		result = append(result, &OutboundHealth{
+			Tag:          s.GetOutboundTag(),
+			Alive:        s.GetAlive(),
+			Delay:        s.GetDelay(),
+			LastError:    s.GetLastErrorReason(),
+			LastSeenTime: s.GetLastSeenTime(),
+			LastTryTime:  s.GetLastTryTime(),
+		})
+	}
+	return result, nil
+}
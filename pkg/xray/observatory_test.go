@@ -0,0 +1,90 @@
+package xray
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBalancerValidation(t *testing.T) {
+	newConfig := func() *Config {
+		config := NewConfig("info")
+		config.Outbounds = append(config.Outbounds,
+			config.MakeShadowsocksOutbound("relay-1", "1.1.1.1", "password", "aes-128-gcm", 1001),
+			config.MakeShadowsocksOutbound("relay-2", "2.2.2.2", "password", "aes-128-gcm", 1002),
+		)
+		config.Routing.Balancers = append(config.Routing.Balancers, &Balancer{
+			Tag:         "relays",
+			Selector:    []string{"relay-"},
+			Strategy:    &BalancerStrategy{Type: "leastPing"},
+			FallbackTag: "out",
+		})
+		return config
+	}
+
+	t.Run("LeastPing Without Observatory", func(t *testing.T) {
+		config := newConfig()
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for leastPing without observatory")
+		}
+	})
+
+	t.Run("LeastPing With Observatory", func(t *testing.T) {
+		config := newConfig()
+		config.EnableObservatory([]string{"relay-"}, "https://www.google.com/generate_204", "1m")
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+		if !slices.Contains(config.API.Services, ObservatoryService) {
+			t.Error("Expected the observatory service to be enabled")
+		}
+	})
+
+	t.Run("LeastLoad Without Burst Observatory", func(t *testing.T) {
+		config := newConfig()
+		config.EnableObservatory([]string{"relay-"}, "", "1m")
+		config.Routing.Balancers[0].Strategy = &BalancerStrategy{Type: "leastLoad"}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for leastLoad without burstObservatory")
+		}
+
+		config.EnableBurstObservatory([]string{"relay-"}, "https://www.google.com/generate_204", "30s", 3)
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Unknown Strategy", func(t *testing.T) {
+		config := newConfig()
+		config.EnableObservatory([]string{"relay-"}, "", "1m")
+		config.Routing.Balancers[0].Strategy.Type = "fastest"
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for unknown strategy")
+		}
+	})
+
+	t.Run("Missing Fallback", func(t *testing.T) {
+		config := newConfig()
+		config.EnableObservatory([]string{"relay-"}, "", "1m")
+		config.Routing.Balancers[0].FallbackTag = "missing"
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for missing fallback outbound")
+		}
+	})
+
+	t.Run("Empty Selector", func(t *testing.T) {
+		config := newConfig()
+		config.EnableObservatory([]string{"relay-"}, "", "1m")
+		config.Routing.Balancers[0].Selector = []string{"backup-"}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for a selector matching no outbound")
+		}
+	})
+
+	t.Run("Invalid Interval", func(t *testing.T) {
+		config := newConfig()
+		config.EnableObservatory([]string{"relay-"}, "", "every minute")
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for invalid probe interval")
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package xray
+
+import (
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+)
+
+// AddPolicyLevel adds (or replaces) a user level with the given idle timeout in seconds and buffer size in KB.
+// User stats are enabled, the node reports the traffic of every user.
+func (c *Config) AddPolicyLevel(level, connIdle, bufferSize int) *PolicyLevels {
+	if c.Policy.Levels == nil {
+		c.Policy.Levels = map[string]*PolicyLevels{}
+	}
+	p := &PolicyLevels{
+		ConnIdle:          &connIdle,
+		BufferSize:        &bufferSize,
+		StatsUserUplink:   true,
+		StatsUserDownlink: true,
+	}
+	c.Policy.Levels[strconv.Itoa(level)] = p
+	return p
+}
+
+// validatePolicy checks the level keys are non-negative integers, Xray stores them as uint32.
+func (c *Config) validatePolicy() error {
+	if c.Policy == nil {
+		return nil
+	}
+	for key := range c.Policy.Levels {
+		if _, err := strconv.ParseUint(key, 10, 32); err != nil {
+			return errors.Errorf("invalid policy level '%s'", key)
+		}
+	}
+	return nil
+}
+
+// validateLevel checks a user level is defined in the policy. Level 0 is always valid, Xray applies its default policy to it.
+func (c *Config) validateLevel(level int) error {
+	if level == 0 {
+		return nil
+	}
+	if level < 0 {
+		return errors.Errorf("invalid level %d", level)
+	}
+	if c.Policy == nil || c.Policy.Levels[strconv.Itoa(level)] == nil {
+		return errors.Errorf("level %d is not defined in the policy", level)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package xray
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPolicyValidation(t *testing.T) {
+	const uuid = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+	t.Run("Premium Level", func(t *testing.T) {
+		config := NewConfig("info")
+		premium := config.AddPolicyLevel(1, 600, 512)
+		handshake := 8
+		premium.Handshake = &handshake
+		inbound := config.MakeVlessInbound("vless", 443, uuid, "tcp", nil)
+		inbound.Settings.Clients[0].Level = 1
+		config.Inbounds = append(config.Inbounds, inbound)
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Undefined Level", func(t *testing.T) {
+		config := NewConfig("info")
+		inbound := config.MakeVlessInbound("vless", 443, uuid, "tcp", nil)
+		inbound.Settings.Clients[0].Level = 2
+		config.Inbounds = append(config.Inbounds, inbound)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for an undefined level")
+		}
+
+		config.Policy.Levels = nil
+		inbound.Settings.Clients[0].Level = 0
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error for the default level: %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		negative := -5
+		for name, modify := range map[string]func(c *Config){
+			"key":         func(c *Config) { c.Policy.Levels["premium"] = &PolicyLevels{} },
+			"conn idle":   func(c *Config) { c.Policy.Levels["0"].ConnIdle = &negative },
+			"buffer size": func(c *Config) { c.Policy.Levels["0"].BufferSize = &negative },
+			"nil level":   func(c *Config) { c.Policy.Levels["1"] = nil },
+		} {
+			config := NewConfig("info")
+			modify(config)
+			if err := config.Validate(); err == nil {
+				t.Errorf("Expected validation error for invalid %s", name)
+			}
+		}
+	})
+}
+
+func TestPolicyJson(t *testing.T) {
+	content := `{"levels":{"0":{"statsUserUplink":true,"statsUserDownlink":true},` +
+		`"1":{"handshake":4,"connIdle":0,"bufferSize":-1,"statsUserOnline":true}},"system":{"statsInboundUplink":true}}`
+	var policy Policy
+	if err := json.Unmarshal([]byte(content), &policy); err != nil {
+		t.Fatal(err)
+	}
+	premium := policy.Levels["1"]
+	if premium.ConnIdle == nil || *premium.ConnIdle != 0 || premium.UplinkOnly != nil || *premium.BufferSize != -1 {
+		t.Errorf("Expected zero options to be kept and unset ones to stay nil, got %+v", premium)
+	}
+
+	output, err := json.Marshal(&policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != content {
+		t.Errorf("Unexpected JSON %s", output)
+	}
+
+	output, _ = json.Marshal(NewConfig("info").Policy)
+	if string(output) != `{"levels":{"0":{"statsUserUplink":true,"statsUserDownlink":true}},`+
+		`"system":{"statsInboundUplink":true,"statsInboundDownlink":true,"statsOutboundUplink":true,"statsOutboundDownlink":true}}` {
+		t.Errorf("Unexpected default policy %s", output)
+	}
+}
@@ -0,0 +1,149 @@
+package xray
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/ebadidev/arch-node/internal/utils"
+	"go.uber.org/zap"
+)
+
+// ProbeResult is the outcome of sending a test request through an outbound.
+type ProbeResult struct {
+	Tag     string `json:"tag"`
+	Url     string `json:"url"`
+	Success bool   `json:"success"`
+	Status  int    `json:"status,omitempty"`
+	Latency int64  `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// MakeProbeConfig creates a config with a local SOCKS inbound routed to the outbound with the given tag.
+// All the outbounds are included, so the outbounds the probed one depends on are available too.
+func (c *Config) MakeProbeConfig(tag string, port int) *Config {
+	return &Config{
+		Log: &Log{LogLevel: "warning"},
+		Inbounds: []*Inbound{
+			{
+				Tag:      "probe",
+				Protocol: "socks",
+				Listen:   "127.0.0.1",
+				Port:     port,
+				Settings: &InboundSettings{},
+			},
+		},
+		Outbounds: c.Outbounds,
+		DNS:       c.DNS,
+		FakeDNS:   c.FakeDNS,
+		Routing: &Routing{
+			DomainStrategy: "AsIs",
+			DomainMatcher:  "hybrid",
+			Rules: []*Rule{
+				{
+					InboundTag:  []string{"probe"},
+					OutboundTag: tag,
+				},
+			},
+		},
+	}
+}
+
+// Probe sends an HTTP GET request to the given URL through the outbound with the given tag.
+// It runs a temporary Xray instance, so the running core is not affected.
+func (x *Xray) Probe(ctx context.Context, tag, target string, timeout time.Duration) (*ProbeResult, error) {
+	config := x.Config()
+	if config.FindOutbound(tag) == nil {
+		return nil, errors.Errorf("outbound '%s' not found", tag)
+	}
+
+	port, err := utils.FreePort()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	content, err := json.Marshal(config.MakeProbeConfig(tag, port))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	file, err := os.CreateTemp("", "xray-probe-*.json")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+	if _, err = file.Write(content); err != nil {
+		_ = file.Close()
+		return nil, errors.WithStack(err)
+	}
+	if err = file.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout+5*time.Second)
+	defer cancel()
+
+	command := exec.CommandContext(ctx, x.binaryPath, "-c", file.Name())
+	if err = command.Start(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		_ = command.Process.Kill()
+		_ = command.Wait()
+	}()
+
+	address := "127.0.0.1:" + strconv.Itoa(port)
+	if err = waitForPort(ctx, address); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	x.l.Debug("xray: probing outbound...", zap.String("tag", tag), zap.String("url", target))
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "socks5", Host: address})},
+	}
+
+	result := &ProbeResult{Tag: tag, Url: target}
+	start := time.Now()
+	response, err := client.Get(target)
+	result.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+
+	result.Status = response.StatusCode
+	result.Success = response.StatusCode < 500
+	if !result.Success {
+		result.Error = fmt.Sprintf("unexpected status %d", response.StatusCode)
+	}
+	return result, nil
+}
+
+// waitForPort waits until the given address accepts TCP connections.
+func waitForPort(ctx context.Context, address string) error {
+	for {
+		if c, err := net.DialTimeout("tcp", address, time.Second); err == nil {
+			return errors.WithStack(c.Close())
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("probe inbound %s not ready", address)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package xray
+
+import (
+	"encoding/json"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// PortList is a comma-separated list of ports and port ranges, e.g. "53,443,1000-2000".
+// Xray accepts a single port as a JSON number or string, it is written as a number.
+type PortList string
+
+func (p *PortList) UnmarshalJSON(content []byte) error {
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		*p = PortList(s)
+		return nil
+	}
+	var port json.Number
+	if err := json.Unmarshal(content, &port); err != nil {
+		return errors.New("port must be a number or a string")
+	}
+	*p = PortList(port.String())
+	return nil
+}
+
+func (p PortList) MarshalJSON() ([]byte, error) {
+	if port, err := strconv.Atoi(string(p)); err == nil {
+		return json.Marshal(port)
+	}
+	return json.Marshal(string(p))
+}
+
+// Validate checks every item is a port or a "from-to" range of ports between 1 and 65535.
+func (p PortList) Validate() error {
+	for _, item := range strings.Split(string(p), ",") {
+		item = strings.TrimSpace(item)
+		from, to, isRange := strings.Cut(item, "-")
+		if !isRange {
+			to = from
+		}
+		first, err1 := strconv.Atoi(from)
+		last, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || first < 1 || last > 65535 || first > last {
+			return errors.Errorf("invalid port or range '%s'", item)
+		}
+	}
+	return nil
+}
+
+// validateRules checks every routing rule, errors name the rule by its ruleTag or its index.
+func (c *Config) validateRules() error {
+	if c.Routing == nil {
+		return nil
+	}
+	for i, rule := range c.Routing.Rules {
+		if err := validateRule(rule); err != nil {
+			name := rule.RuleTag
+			if name == "" {
+				name = strconv.Itoa(i)
+			}
+			return errors.Wrapf(err, "invalid rule %s", name)
+		}
+	}
+	return nil
+}
+
+// validateRule checks the rule has a target and at least one matcher, and the matcher syntax:
+//   - domain: plain keyword, "domain:", "full:", "keyword:", "regexp:", "dotless:", "geosite:" or "ext:file:tag"
+//   - ip and source: IP, CIDR, "geoip:" (optionally negated "geoip:!") or "ext:file:tag"
+//   - port and sourcePort: ports and ranges, e.g. "53,443,1000-2000"
+//   - network: "tcp", "udp" or "tcp,udp"
+//   - user: emails or "regexp:" patterns, attrs: header names mapped to regular expressions
+func validateRule(rule *Rule) error {
+	if rule.OutboundTag == "" && rule.BalancerTag == "" {
+		return errors.New("either outboundTag or balancerTag is required")
+	}
+	if len(rule.InboundTag) == 0 && len(rule.Domain) == 0 && len(rule.IP) == 0 && rule.Port == "" &&
+		rule.SourcePort == "" && len(rule.Source) == 0 && rule.Network == "" && len(rule.Protocol) == 0 &&
+		len(rule.User) == 0 && len(rule.Attrs) == 0 {
+		return errors.New("at least one matcher is required")
+	}
+
+	for _, domain := range rule.Domain {
+		if err := validateDomainMatcher(domain); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, ip := range append(append([]string{}, rule.IP...), rule.Source...) {
+		if err := validateIpMatcher(ip); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, ports := range []PortList{rule.Port, rule.SourcePort} {
+		if ports == "" {
+			continue
+		}
+		if err := ports.Validate(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if rule.Network != "" {
+		networks := strings.Split(rule.Network, ",")
+		for i, n := range networks {
+			if networks[i] = strings.TrimSpace(n); networks[i] != "tcp" && networks[i] != "udp" {
+				return errors.Errorf("invalid network '%s'", rule.Network)
+			}
+		}
+		// Xray does not trim the items of a network list, so they are written without spaces.
+		rule.Network = strings.Join(networks, ",")
+	}
+	for _, user := range rule.User {
+		if user == "" {
+			return errors.New("empty user")
+		}
+		if pattern, found := strings.CutPrefix(user, "regexp:"); found {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return errors.Wrapf(err, "invalid user pattern '%s'", user)
+			}
+		}
+	}
+	for key, pattern := range rule.Attrs {
+		if key == "" {
+			return errors.New("empty attribute name")
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.Wrapf(err, "invalid attribute pattern '%s'", pattern)
+		}
+	}
+	return nil
+}
+
+func validateDomainMatcher(domain string) error {
+	prefix, value, found := strings.Cut(domain, ":")
+	if !found {
+		if domain == "" {
+			return errors.New("empty domain")
+		}
+		return nil
+	}
+
+	switch prefix {
+	case "geosite":
+		if category, _, _ := strings.Cut(value, "@"); category == "" {
+			return errors.Errorf("invalid domain '%s', the geosite category is missing", domain)
+		}
+	case "ext", "ext-domain":
+		if !validExternal(value) {
+			return errors.Errorf("invalid domain '%s', expected %s:file:tag", domain, prefix)
+		}
+	case "regexp":
+		if _, err := regexp.Compile(value); err != nil {
+			return errors.Wrapf(err, "invalid domain '%s'", domain)
+		}
+	case "domain", "full", "keyword":
+		if value == "" {
+			return errors.Errorf("invalid domain '%s', the value is missing", domain)
+		}
+	case "dotless":
+		if strings.Contains(value, ".") {
+			return errors.Errorf("invalid domain '%s', dotless values cannot contain a dot", domain)
+		}
+	}
+	return nil
+}
+
+func validateIpMatcher(ip string) error {
+	if code, found := strings.CutPrefix(ip, "geoip:"); found {
+		if strings.TrimPrefix(code, "!") == "" {
+			return errors.Errorf("invalid ip '%s', the geoip code is missing", ip)
+		}
+		return nil
+	}
+	for _, prefix := range []string{"ext:", "ext-ip:"} {
+		if value, found := strings.CutPrefix(ip, prefix); found {
+			if !validExternal(value) {
+				return errors.Errorf("invalid ip '%s', expected %sfile:tag", ip, prefix)
+			}
+			return nil
+		}
+	}
+	if strings.Contains(ip, "/") {
+		if _, _, err := net.ParseCIDR(ip); err != nil {
+			return errors.Errorf("invalid CIDR '%s'", ip)
+		}
+		return nil
+	}
+	if net.ParseIP(ip) == nil {
+		return errors.Errorf("invalid ip '%s'", ip)
+	}
+	return nil
+}
+
+// GeoReferences returns the geosite categories and geoip codes the routing rules and the DNS reference, which must exist in the data files.
+func (c *Config) GeoReferences() (sites, ips []string) {
+	addSite := func(domain string) {
+		if value, found := strings.CutPrefix(domain, "geosite:"); found {
+			category, _, _ := strings.Cut(value, "@")
+			sites = append(sites, category)
+		}
+	}
+	addIp := func(ip string) {
+		if code, found := strings.CutPrefix(ip, "geoip:"); found {
+			ips = append(ips, strings.TrimPrefix(code, "!"))
+		}
+	}
+
+	if c.Routing != nil {
+		for _, rule := range c.Routing.Rules {
+			for _, domain := range rule.Domain {
+				addSite(domain)
+			}
+			for _, ip := range append(append([]string{}, rule.IP...), rule.Source...) {
+				addIp(ip)
+			}
+		}
+	}
+	if c.DNS != nil {
+		for _, server := range c.DNS.Servers {
+			if server == nil {
+				continue
+			}
+			for _, domain := range server.Domains {
+				addSite(domain)
+			}
+			for _, ip := range server.ExpectIPs {
+				addIp(ip)
+			}
+		}
+		for domain := range c.DNS.Hosts {
+			addSite(domain)
+		}
+	}
+	return sites, ips
+}
+
+// validExternal reports whether the value of an external data matcher is "file:tag".
+func validExternal(value string) bool {
+	file, tag, found := strings.Cut(value, ":")
+	return found && file != "" && tag != "" && !strings.Contains(tag, ":")
+}
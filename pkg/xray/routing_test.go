@@ -0,0 +1,105 @@
+package xray
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestRuleValidation(t *testing.T) {
+	newConfig := func(rule *Rule) *Config {
+		config := NewConfig("info")
+		config.Outbounds = append(config.Outbounds, &Outbound{Tag: "block", Protocol: "blackhole"})
+		config.Routing.Rules = append(config.Routing.Rules, rule)
+		return config
+	}
+
+	valid := map[string]*Rule{
+		"Geoip":       {IP: []string{"geoip:private", "geoip:!ir"}, OutboundTag: "block"},
+		"CIDR":        {IP: []string{"10.0.0.0/8", "fd00::/8", "1.1.1.1"}, OutboundTag: "block"},
+		"Source":      {Source: []string{"192.168.1.0/24"}, SourcePort: "1024-65535", OutboundTag: "out"},
+		"Domain":      {Domain: []string{"geosite:category-ads-all", "full:www.example.com", "domain:example.org", "regexp:\\.ir$", "keyword:ads", "example", "ext:custom.dat:ads"}, OutboundTag: "block"},
+		"Port":        {Port: "53,443,1000-2000", Network: "tcp,udp", OutboundTag: "out"},
+		"BitTorrent":  {Protocol: []string{"bittorrent"}, OutboundTag: "block", RuleTag: "no-bt"},
+		"User":        {User: []string{"alice@example.com", "regexp:@premium\\.example$"}, OutboundTag: "out"},
+		"Attrs":       {Attrs: map[string]string{":method": "GET", ":path": "^/api"}, OutboundTag: "out"},
+		"Matcher":     {Domain: []string{"example.com"}, DomainMatcher: "linear", OutboundTag: "out"},
+		"Without Tag": {Network: "udp", OutboundTag: "out"},
+		"Spaced":      {Network: "tcp, udp", OutboundTag: "out"},
+	}
+	for name, rule := range valid {
+		t.Run(name, func(t *testing.T) {
+			if err := newConfig(rule).Validate(); err != nil {
+				t.Errorf("Unexpected validation error: %v", err)
+			}
+		})
+	}
+
+	spaced := &Rule{Network: " tcp , udp", OutboundTag: "out"}
+	if err := newConfig(spaced).Validate(); err != nil || spaced.Network != "tcp,udp" {
+		t.Errorf("Expected the network list to be accepted without spaces, got '%s' (%v)", spaced.Network, err)
+	}
+
+	invalid := map[string]*Rule{
+		"No Target":       {InboundTag: []string{"api"}},
+		"No Matcher":      {OutboundTag: "out"},
+		"Bad CIDR":        {IP: []string{"10.0.0.0/33"}, OutboundTag: "block"},
+		"Bad IP":          {IP: []string{"example.com"}, OutboundTag: "block"},
+		"Empty Geoip":     {IP: []string{"geoip:"}, OutboundTag: "block"},
+		"Empty Geosite":   {Domain: []string{"geosite:"}, OutboundTag: "block"},
+		"Bad Regexp":      {Domain: []string{"regexp:("}, OutboundTag: "block"},
+		"Empty Full":      {Domain: []string{"full:"}, OutboundTag: "block"},
+		"Bad External":    {Domain: []string{"ext:custom.dat"}, OutboundTag: "block"},
+		"Reversed Range":  {Port: "2000-1000", OutboundTag: "out"},
+		"Port Overflow":   {Port: "65536", OutboundTag: "out"},
+		"Bad Port":        {SourcePort: "http", OutboundTag: "out"},
+		"Bad Network":     {Network: "tcp, icmp", OutboundTag: "out"},
+		"Bad Protocol":    {Protocol: []string{"ssh"}, OutboundTag: "out"},
+		"Bad User":        {User: []string{"regexp:["}, OutboundTag: "out"},
+		"Bad Attribute":   {Attrs: map[string]string{":path": "("}, OutboundTag: "out"},
+		"Bad Matcher":     {Domain: []string{"example.com"}, DomainMatcher: "mph", OutboundTag: "out"},
+		"Missing Balance": {Network: "tcp", BalancerTag: "missing"},
+	}
+	for name, rule := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if err := newConfig(rule).Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestPortListJson(t *testing.T) {
+	var rules []*Rule
+	content := `[{"port":53,"outboundTag":"out"},{"port":"53,443","sourcePort":"1000-2000","outboundTag":"out"}]`
+	if err := json.Unmarshal([]byte(content), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if rules[0].Port != "53" || rules[1].Port != "53,443" || rules[1].SourcePort != "1000-2000" {
+		t.Errorf("Unexpected ports %s %s %s", rules[0].Port, rules[1].Port, rules[1].SourcePort)
+	}
+
+	output, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != `[{"outboundTag":"out","port":53},{"outboundTag":"out","port":"53,443","sourcePort":"1000-2000"}]` {
+		t.Errorf("Unexpected JSON %s", output)
+	}
+}
+
+func TestGeoReferences(t *testing.T) {
+	config := NewConfig("info")
+	config.Routing.Rules = append(config.Routing.Rules,
+		&Rule{Domain: []string{"geosite:category-ads-all@ads", "full:example.com"}, IP: []string{"geoip:!ir"}, OutboundTag: "out"},
+		&Rule{Source: []string{"geoip:private", "10.0.0.0/8"}, Domain: []string{"geosite:ir"}, OutboundTag: "out"},
+	)
+
+	sites, ips := config.GeoReferences()
+	if !slices.Equal(sites, []string{"category-ads-all", "ir"}) {
+		t.Errorf("Unexpected geosite categories %v", sites)
+	}
+	if !slices.Equal(ips, []string{"ir", "private"}) {
+		t.Errorf("Unexpected geoip codes %v", ips)
+	}
+}
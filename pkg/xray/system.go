@@ -0,0 +1,156 @@
+package xray
+
+import (
+	"net"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// MakeSocksInbound creates a SOCKS5 inbound, accounts enable password authentication and udp enables UDP associate.
+// Without accounts it only listens on the loopback address, as it would be an open proxy.
+func (c *Config) MakeSocksInbound(tag string, port int, accounts []*Account, udp bool) *Inbound {
+	settings := &InboundSettings{
+		Auth:     "noauth",
+		Accounts: accounts,
+		UDP:      udp,
+	}
+	if len(accounts) > 0 {
+		settings.Auth = "password"
+	}
+
+	return &Inbound{
+		Tag:      tag,
+		Protocol: "socks",
+		Listen:   proxyListen(accounts),
+		Port:     port,
+		Settings: settings,
+		Sniffing: c.MakeSniffing(),
+	}
+}
+
+// MakeHttpInbound creates an HTTP proxy inbound, accounts enable basic authentication.
+// Without accounts it only listens on the loopback address, as it would be an open proxy.
+func (c *Config) MakeHttpInbound(tag string, port int, accounts []*Account) *Inbound {
+	return &Inbound{
+		Tag:      tag,
+		Protocol: "http",
+		Listen:   proxyListen(accounts),
+		Port:     port,
+		Settings: &InboundSettings{
+			Accounts: accounts,
+		},
+		Sniffing: c.MakeSniffing(),
+	}
+}
+
+// proxyListen returns the listen address of a SOCKS or HTTP proxy inbound, the loopback address when it has no accounts.
+func proxyListen(accounts []*Account) string {
+	if len(accounts) == 0 {
+		return "127.0.0.1"
+	}
+	return "0.0.0.0"
+}
+
+// localListen reports whether the listen address is only reachable from this server: a loopback IP or a unix socket.
+// An empty address listens on all of them.
+func localListen(listen string) bool {
+	if strings.HasPrefix(listen, "/") || strings.HasPrefix(listen, "@") {
+		return true
+	}
+	ip := net.ParseIP(listen)
+	return ip != nil && ip.IsLoopback()
+}
+
+// MakeDokodemoInbound creates a dokodemo-door inbound forwarding the connections of network ("tcp", "udp" or "tcp,udp") to address:targetPort.
+func (c *Config) MakeDokodemoInbound(tag string, port int, address string, targetPort int, network string) *Inbound {
+	return &Inbound{
+		Tag:      tag,
+		Protocol: "dokodemo-door",
+		Listen:   "0.0.0.0",
+		Port:     port,
+		Settings: &InboundSettings{
+			Address: address,
+			Port:    targetPort,
+			Network: network,
+		},
+	}
+}
+
+// MakeSocksOutbound creates a SOCKS5 outbound, user and pass are optional.
+func (c *Config) MakeSocksOutbound(tag, address string, port int, user, pass string) *Outbound {
+	return makeProxyOutbound("socks", tag, address, port, user, pass)
+}
+
+// MakeHttpOutbound creates an HTTP proxy outbound (CONNECT), user and pass are optional.
+func (c *Config) MakeHttpOutbound(tag, address string, port int, user, pass string) *Outbound {
+	return makeProxyOutbound("http", tag, address, port, user, pass)
+}
+
+func makeProxyOutbound(protocol, tag, address string, port int, user, pass string) *Outbound {
+	server := &OutboundServer{
+		Address: address,
+		Port:    port,
+	}
+	if user != "" {
+		server.Users = []*Account{{User: user, Pass: pass}}
+	}
+
+	return &Outbound{
+		Tag:      tag,
+		Protocol: protocol,
+		Settings: &OutboundSettings{
+			Servers: []*OutboundServer{server},
+		},
+	}
+}
+
+// validateInboundSettings checks the settings of the SOCKS, HTTP and dokodemo-door inbounds:
+// SOCKS accounts go with the password auth, proxies without accounts only listen on loopback, account users are unique
+// and dokodemo-door forwards to a valid address and network.
+func validateInboundSettings(inbound *Inbound) error {
+	settings := inbound.Settings
+	if (inbound.Protocol == "socks" || inbound.Protocol == "http") && (settings == nil || len(settings.Accounts) == 0) &&
+		!localListen(inbound.Listen) {
+		return errors.Errorf("a %s inbound without accounts must listen on loopback, the proxy would be open", inbound.Protocol)
+	}
+	if settings == nil {
+		return nil
+	}
+
+	switch inbound.Protocol {
+	case "socks":
+		if settings.Auth == "password" && len(settings.Accounts) == 0 {
+			return errors.New("password auth requires accounts")
+		}
+		if settings.Auth != "password" && len(settings.Accounts) > 0 {
+			return errors.New("accounts require password auth, the proxy would be open")
+		}
+		return validateAccounts(settings.Accounts)
+	case "http":
+		return validateAccounts(settings.Accounts)
+	case "dokodemo-door":
+		if settings.Address != "" && net.ParseIP(settings.Address) == nil && !validHostname(settings.Address) {
+			return errors.Errorf("invalid address '%s'", settings.Address)
+		}
+		if settings.Network != "" {
+			for _, n := range strings.Split(settings.Network, ",") {
+				if n = strings.TrimSpace(n); n != "tcp" && n != "udp" {
+					return errors.Errorf("invalid network '%s'", settings.Network)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateAccounts(accounts []*Account) error {
+	seen := map[string]bool{}
+	for _, a := range accounts {
+		if seen[a.User] {
+			return errors.Errorf("duplicate account %s", a.User)
+		}
+		seen[a.User] = true
+	}
+	return nil
+}
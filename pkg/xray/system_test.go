@@ -0,0 +1,107 @@
+package xray
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSystemProtocols(t *testing.T) {
+	t.Run("Builders", func(t *testing.T) {
+		config := NewConfig("info")
+		socks := config.MakeSocksInbound("socks", 1080, []*Account{{User: "alice", Pass: "secret"}}, true)
+		socks.Settings.IP = "127.0.0.1"
+		http := config.MakeHttpInbound("http", 8080, nil)
+		http.Settings.AllowTransparent = true
+		config.Inbounds = append(config.Inbounds, socks, http, config.MakeDokodemoInbound("dns", 5353, "1.1.1.1", 53, "tcp,udp"))
+		config.Outbounds = append(config.Outbounds,
+			config.MakeSocksOutbound("upstream-socks", "proxy.example.com", 1080, "bob", "secret"),
+			config.MakeHttpOutbound("upstream-http", "proxy.example.com", 3128, "", ""),
+		)
+		if socks.Settings.Auth != "password" {
+			t.Errorf("Expected password auth with accounts, got %s", socks.Settings.Auth)
+		}
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for name, inbound := range map[string]func(c *Config) *Inbound{
+			"open accounts": func(c *Config) *Inbound {
+				i := c.MakeSocksInbound("socks", 1080, []*Account{{User: "alice", Pass: "secret"}}, false)
+				i.Settings.Auth = "noauth"
+				return i
+			},
+			"password without accounts": func(c *Config) *Inbound {
+				i := c.MakeSocksInbound("socks", 1080, nil, false)
+				i.Settings.Auth = "password"
+				return i
+			},
+			"auth": func(c *Config) *Inbound {
+				i := c.MakeSocksInbound("socks", 1080, nil, false)
+				i.Settings.Auth = "basic"
+				return i
+			},
+			"ip": func(c *Config) *Inbound {
+				i := c.MakeSocksInbound("socks", 1080, nil, true)
+				i.Settings.IP = "example.com"
+				return i
+			},
+			"duplicate account": func(c *Config) *Inbound {
+				return c.MakeHttpInbound("http", 8080, []*Account{{User: "alice", Pass: "a"}, {User: "alice", Pass: "b"}})
+			},
+			"empty pass": func(c *Config) *Inbound {
+				return c.MakeHttpInbound("http", 8080, []*Account{{User: "alice"}})
+			},
+			"clients": func(c *Config) *Inbound {
+				i := c.MakeHttpInbound("http", 8080, nil)
+				i.Settings.Clients = []*Client{{Email: "alice@example.com", Password: "secret"}}
+				return i
+			},
+			"open socks": func(c *Config) *Inbound {
+				i := c.MakeSocksInbound("socks", 1080, nil, false)
+				i.Listen = "0.0.0.0"
+				return i
+			},
+			"open http": func(c *Config) *Inbound {
+				i := c.MakeHttpInbound("http", 8080, nil)
+				i.Listen = ""
+				return i
+			},
+			"open socks without settings": func(c *Config) *Inbound {
+				return &Inbound{Tag: "socks", Protocol: "socks", Listen: "203.0.113.5", Port: 1080}
+			},
+			"dokodemo network": func(c *Config) *Inbound { return c.MakeDokodemoInbound("dns", 5353, "1.1.1.1", 53, "icmp") },
+			"dokodemo port":    func(c *Config) *Inbound { return c.MakeDokodemoInbound("dns", 5353, "1.1.1.1", 70000, "udp") },
+			"dokodemo address": func(c *Config) *Inbound { return c.MakeDokodemoInbound("dns", 5353, "not an address", 53, "udp") },
+		} {
+			config := NewConfig("info")
+			config.Inbounds = append(config.Inbounds, inbound(config))
+			if err := config.Validate(); err == nil {
+				t.Errorf("Expected validation error for invalid %s", name)
+			}
+		}
+	})
+
+	t.Run("Outbound User Level", func(t *testing.T) {
+		config := NewConfig("info")
+		outbound := config.MakeSocksOutbound("upstream", "proxy.example.com", 1080, "bob", "secret")
+		outbound.Settings.Servers[0].Users[0].Level = 3
+		config.Outbounds = append(config.Outbounds, outbound)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for an undefined user level")
+		}
+	})
+}
+
+func TestAccountJson(t *testing.T) {
+	var outbound Outbound
+	content := `{"protocol":"socks","tag":"upstream","settings":{"servers":[{"address":"proxy.example.com","port":1080,"users":[{"user":"bob","pass":"secret"}]}]}}`
+	if err := json.Unmarshal([]byte(content), &outbound); err != nil {
+		t.Fatal(err)
+	}
+	users := outbound.Settings.Servers[0].Users
+	if len(users) != 1 || users[0].User != "bob" || users[0].Pass != "secret" {
+		t.Errorf("Unexpected users %v", users)
+	}
+}
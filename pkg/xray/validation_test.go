@@ -115,3 +115,42 @@ func TestProtocolValidation(t *testing.T) {
 		config.Inbounds = config.Inbounds[:len(config.Inbounds)-1]
 	})
 }
+
+func TestSniffing(t *testing.T) {
+	t.Run("Builder Default", func(t *testing.T) {
+		config := NewConfig("info")
+		inbound := config.MakeVmessInbound("vmess", 10001, "uuid", "auto", nil)
+		if inbound.Sniffing == nil || !inbound.Sniffing.Enabled || !inbound.Sniffing.RouteOnly {
+			t.Fatal("Expected sniffing to be enabled for routing by default")
+		}
+		if len(inbound.Sniffing.DestOverride) != 3 {
+			t.Errorf("Expected http, tls and quic, got %v", inbound.Sniffing.DestOverride)
+		}
+		config.Inbounds = append(config.Inbounds, inbound)
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		config := NewConfig("info")
+		inbound := config.MakeTrojanInbound("trojan", 10002, "password", "tcp", nil)
+		inbound.Sniffing = config.MakeSniffing("fakedns", "http")
+		inbound.Sniffing.DomainsExcluded = []string{"courier.push.apple.com"}
+		config.Inbounds = append(config.Inbounds, inbound)
+		config.EnableFakeDns("198.18.0.0/15", 65535)
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Invalid Dest Override", func(t *testing.T) {
+		config := NewConfig("info")
+		inbound := config.MakeVlessInbound("vless", 10003, "uuid", "tcp", nil)
+		inbound.Sniffing = config.MakeSniffing("dns")
+		config.Inbounds = append(config.Inbounds, inbound)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for unknown destOverride")
+		}
+	})
+}
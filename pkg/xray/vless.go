@@ -0,0 +1,124 @@
+package xray
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	FlowVision       = "xtls-rprx-vision"
+	FlowVisionUdp443 = "xtls-rprx-vision-udp443"
+)
+
+// FallbackDest is a fallback destination: a port, an "address:port" or a Unix socket path ("/path" or "@abstract").
+// Xray accepts ports as JSON numbers or strings, they are always written as numbers.
+type FallbackDest string
+
+func (d *FallbackDest) UnmarshalJSON(content []byte) error {
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		*d = FallbackDest(s)
+		return nil
+	}
+	var port json.Number
+	if err := json.Unmarshal(content, &port); err != nil {
+		return errors.New("fallback dest must be a number or a string")
+	}
+	*d = FallbackDest(port.String())
+	return nil
+}
+
+func (d FallbackDest) MarshalJSON() ([]byte, error) {
+	if port, err := strconv.Atoi(string(d)); err == nil {
+		return json.Marshal(port)
+	}
+	return json.Marshal(string(d))
+}
+
+// Validate checks the destination is a valid port, "address:port" or Unix socket path.
+func (d FallbackDest) Validate() error {
+	s := string(d)
+	if strings.HasPrefix(s, "/") || strings.HasPrefix(s, "@") {
+		return nil
+	}
+	portString := s
+	if strings.Contains(s, ":") {
+		host, p, err := net.SplitHostPort(s)
+		if err != nil || host == "" {
+			return errors.Errorf("invalid fallback dest %s", s)
+		}
+		portString = p
+	}
+	if port, err := strconv.Atoi(portString); err != nil || port < 1 || port > 65535 {
+		return errors.Errorf("invalid fallback dest %s", s)
+	}
+	return nil
+}
+
+// streamTransport returns the network and security of the stream settings, Xray defaults to raw TCP without security.
+func streamTransport(s *StreamSettings) (network, security string) {
+	network = "tcp"
+	if s != nil {
+		if s.Network != "" {
+			network = s.Network
+		}
+		security = s.Security
+	}
+	return network, security
+}
+
+// validateFlow checks an XTLS flow is used only by VLESS over raw TCP secured by TLS or REALITY.
+func validateFlow(flow, protocol string, s *StreamSettings) error {
+	if flow == "" {
+		return nil
+	}
+	if protocol != "vless" {
+		return errors.Errorf("flow %s is only supported by vless", flow)
+	}
+	network, security := streamTransport(s)
+	if network != "tcp" && network != "raw" {
+		return errors.Errorf("flow %s requires tcp (raw) transport, not %s", flow, network)
+	}
+	if security != "tls" && security != "reality" {
+		return errors.Errorf("flow %s requires tls or reality security", flow)
+	}
+	return nil
+}
+
+// validateFallbacks checks the fallbacks are set on a VLESS or Trojan inbound over raw TCP,
+// their destinations are valid, SNI and ALPN matching have TLS or REALITY to read them from, and no two fallbacks match the same connections.
+func validateFallbacks(inbound *Inbound) error {
+	if inbound.Settings == nil || len(inbound.Settings.Fallbacks) == 0 {
+		return nil
+	}
+	if inbound.Protocol != "vless" && inbound.Protocol != "trojan" {
+		return errors.Errorf("fallbacks are only supported by vless and trojan, not %s", inbound.Protocol)
+	}
+	network, security := streamTransport(inbound.StreamSettings)
+	if network != "tcp" && network != "raw" {
+		return errors.Errorf("fallbacks require tcp (raw) transport, not %s", network)
+	}
+
+	seen := map[string]bool{}
+	for _, f := range inbound.Settings.Fallbacks {
+		if err := f.Dest.Validate(); err != nil {
+			return errors.WithStack(err)
+		}
+		if (f.Name != "" || f.Alpn != "") && security != "tls" && security != "reality" {
+			return errors.New("fallback name and alpn require tls or reality security")
+		}
+		if f.Path != "" && !strings.HasPrefix(f.Path, "/") {
+			return errors.Errorf("fallback path %s must start with /", f.Path)
+		}
+		key := f.Name + "|" + f.Alpn + "|" + f.Path
+		if seen[key] {
+			return errors.Errorf("duplicate fallback for name %q, alpn %q and path %q", f.Name, f.Alpn, f.Path)
+		}
+		seen[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package xray
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVisionValidation(t *testing.T) {
+	const uuid = "b831381d-6324-4d53-ad4f-8cda48b30811"
+
+	newConfig := func(streamSettings *StreamSettings, fallbacks []*Fallback) *Config {
+		config := NewConfig("info")
+		config.Inbounds = append(config.Inbounds, config.MakeVlessVisionInbound("vision", 443, uuid, streamSettings, fallbacks))
+		return config
+	}
+	reality := func() *StreamSettings {
+		return NewConfig("info").AddRealityToStreamSettings(nil, "www.microsoft.com:443", []string{"www.microsoft.com"}, "private", "")
+	}
+
+	t.Run("Reality", func(t *testing.T) {
+		config := newConfig(reality(), nil)
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Without Security", func(t *testing.T) {
+		config := newConfig(&StreamSettings{Network: "tcp"}, nil)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for vision without tls or reality")
+		}
+	})
+
+	t.Run("WebSocket", func(t *testing.T) {
+		config := NewConfig("info")
+		ws := config.AddTlsToStreamSettings(config.MakeWebSocketStreamSettings("/ws", "example.com"), "example.com", false)
+		config.Inbounds = append(config.Inbounds, config.MakeVlessVisionInbound("vision", 443, uuid, ws, nil))
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for vision over websocket")
+		}
+	})
+
+	t.Run("VMess Flow", func(t *testing.T) {
+		config := NewConfig("info")
+		inbound := config.MakeVmessInbound("vmess", 443, uuid, "auto", config.AddTlsToStreamSettings(nil, "example.com", false))
+		inbound.Settings.Clients[0].Flow = FlowVision
+		config.Inbounds = append(config.Inbounds, inbound)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for flow on vmess")
+		}
+	})
+
+	t.Run("Outbound", func(t *testing.T) {
+		config := NewConfig("info")
+		config.Outbounds = append(config.Outbounds, config.MakeVlessVisionOutbound("relay", "relay.example.com", 443, uuid, reality()))
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+
+		config.Outbounds[1].StreamSettings = &StreamSettings{Network: "grpc", Security: "tls"}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for vision over grpc")
+		}
+	})
+
+	t.Run("Fallbacks", func(t *testing.T) {
+		config := newConfig(reality(), nil)
+		fallbacks := []*Fallback{
+			config.MakeFallback("8080", "", "", 1),
+			config.MakeFallback("/dev/shm/h2.sock", "h2", "", 2),
+			config.MakeFallback("127.0.0.1:2001", "", "/ws", 0),
+			config.MakeFallback("@nginx", "http/1.1", "/api", 0),
+		}
+		config.Inbounds[1].Settings.Fallbacks = fallbacks
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Invalid Fallbacks", func(t *testing.T) {
+		config := NewConfig("info")
+		for name, fallback := range map[string]*Fallback{
+			"port":  config.MakeFallback("70000", "", "", 0),
+			"host":  config.MakeFallback("example.com", "", "", 0),
+			"alpn":  config.MakeFallback("8080", "h3", "", 0),
+			"path":  config.MakeFallback("8080", "", "ws", 0),
+			"xver":  config.MakeFallback("8080", "", "", 3),
+			"empty": config.MakeFallback("", "", "", 0),
+		} {
+			config := newConfig(reality(), []*Fallback{fallback})
+			if err := config.Validate(); err == nil {
+				t.Errorf("Expected validation error for invalid fallback %s", name)
+			}
+		}
+	})
+
+	t.Run("Duplicate Fallbacks", func(t *testing.T) {
+		config := newConfig(reality(), nil)
+		config.Inbounds[1].Settings.Fallbacks = []*Fallback{
+			config.MakeFallback("8080", "", "/ws", 0),
+			config.MakeFallback("8081", "", "/ws", 0),
+		}
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for duplicate fallbacks")
+		}
+	})
+
+	t.Run("ALPN Without Security", func(t *testing.T) {
+		config := NewConfig("info")
+		inbound := config.MakeVlessInbound("vless", 443, uuid, "tcp", nil)
+		inbound.Settings.Fallbacks = []*Fallback{config.MakeFallback("8080", "h2", "", 0)}
+		config.Inbounds = append(config.Inbounds, inbound)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for alpn fallback without tls")
+		}
+
+		inbound.Settings.Fallbacks[0].Alpn = ""
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected validation error: %v", err)
+		}
+	})
+
+	t.Run("Shadowsocks Fallbacks", func(t *testing.T) {
+		config := NewConfig("info")
+		inbound := config.MakeShadowsocksInbound("ss", "password", "aes-128-gcm", "tcp", 1080, nil)
+		inbound.Settings.Fallbacks = []*Fallback{config.MakeFallback("8080", "", "", 0)}
+		config.Inbounds = append(config.Inbounds, inbound)
+		if err := config.Validate(); err == nil {
+			t.Error("Expected validation error for fallbacks on shadowsocks")
+		}
+	})
+}
+
+func TestFallbackDestJson(t *testing.T) {
+	var fallbacks []*Fallback
+	if err := json.Unmarshal([]byte(`[{"dest":8080},{"dest":"8081"},{"dest":"/dev/shm/h2.sock"}]`), &fallbacks); err != nil {
+		t.Fatal(err)
+	}
+	if fallbacks[0].Dest != "8080" || fallbacks[1].Dest != "8081" || fallbacks[2].Dest != "/dev/shm/h2.sock" {
+		t.Errorf("Unexpected destinations %s %s %s", fallbacks[0].Dest, fallbacks[1].Dest, fallbacks[2].Dest)
+	}
+
+	content, err := json.Marshal(fallbacks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `[{"dest":8080},{"dest":8081},{"dest":"/dev/shm/h2.sock"}]` {
+		t.Errorf("Unexpected JSON %s", content)
+	}
+
+	if err = json.Unmarshal([]byte(`[{"dest":true}]`), &fallbacks); err == nil {
+		t.Error("Expected error for a boolean dest")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +19,8 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// Xray runs the core process. The locker serializes the operations on the process (run, close and restart),
+// while the state locker guards the fields other goroutines read, so reading the status never waits for a restart.
 type Xray struct {
 	l          *logger.Logger
 	config     *Config
@@ -26,7 +29,20 @@ type Xray struct {
 	command    *exec.Cmd
 	connection *grpc.ClientConn
 	locker     *sync.Mutex
+	state      *sync.RWMutex
 	context    context.Context
+	version    string
+	startedAt  time.Time
+}
+
+// ErrBusy is returned when an exclusive operation is requested while another one is in progress.
+var ErrBusy = errors.New("xray: another operation is in progress")
+
+type Status struct {
+	Running   bool   `json:"running"`
+	Pid       int    `json:"pid,omitempty"`
+	StartedAt int64  `json:"started_at,omitempty"`
+	Version   string `json:"version,omitempty"`
 }
 
 func (x *Xray) loadConfig() error {
@@ -51,39 +67,45 @@ func (x *Xray) loadConfig() error {
 		return errors.WithStack(err)
 	}
 
-	x.config = &newConfig
+	x.SetConfig(&newConfig)
 	x.l.Debug("xray: config file loaded")
 	return nil
 }
 
-func (x *Xray) saveConfig() error {
-	x.l.Debug("xray: saving config file...")
+func (x *Xray) saveConfig(l *logger.Logger) error {
+	l.Debug("xray: saving config file...")
 
-	content, err := json.Marshal(x.config)
+	content, err := json.Marshal(x.Config())
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
 	err = os.WriteFile(x.configPath, content, 0755)
 	if err == nil {
-		x.l.Debug("xray: config file saved")
+		l.Debug("xray: config file saved")
 	}
 	return errors.WithStack(err)
 }
 
 func (x *Xray) Run() error {
-	x.l.Debug("xray: running...")
-
 	x.locker.Lock()
 	defer x.locker.Unlock()
 
-	if err := x.saveConfig(); err != nil {
+	return x.run(x.l)
+}
+
+func (x *Xray) run(l *logger.Logger) error {
+	l.Debug("xray: running...")
+
+	if err := x.saveConfig(l); err != nil {
 		return errors.WithStack(err)
 	}
 
-	go x.runCore()
+	if err := x.start(l); err != nil {
+		return errors.WithStack(err)
+	}
 
-	err := x.connect()
+	err := x.connect(l)
 	return errors.WithStack(err)
 }
 
@@ -92,67 +114,138 @@ func (x *Xray) Init() error {
 	return errors.WithStack(err)
 }
 
-func (x *Xray) runCore() {
-	x.l.Debug("xray: running core...")
+// start starts the core process and waits for it in the background.
+func (x *Xray) start(l *logger.Logger) error {
+	l.Debug("xray: running core...")
 
 	if !utils.FileExist(x.binaryPath) {
-		x.l.Fatal("xray: binary not found", zap.String("path", x.binaryPath))
+		return errors.Errorf("xray: binary not found at %s", x.binaryPath)
 	}
 
-	x.command = exec.Command(x.binaryPath, "-c", x.configPath)
-	x.command.Stderr = os.Stderr
-	x.command.Stdout = os.Stdout
+	command := exec.Command(x.binaryPath, "-c", x.configPath)
+	command.Stderr = os.Stderr
+	command.Stdout = os.Stdout
 
-	x.l.Info("xray: executing the binary...", zap.String("path", x.binaryPath))
-	if err := x.command.Run(); err != nil && err.Error() != "signal: killed" {
+	l.Info("xray: executing the binary...", zap.String("path", x.binaryPath))
+	if err := command.Start(); err != nil {
+		return errors.Wrap(err, "xray: cannot execute the binary")
+	}
+
+	x.state.Lock()
+	x.command = command
+	x.startedAt = time.Now()
+	x.state.Unlock()
+
+	go x.wait(command)
+	return nil
+}
+
+// wait waits for the core process to exit, it is fatal unless the process is killed by close.
+// It logs with the core logger, the process outlives the request that started it.
+func (x *Xray) wait(command *exec.Cmd) {
+	if err := command.Wait(); err != nil && err.Error() != "signal: killed" {
 		x.l.Fatal("xray: cannot execute the binary", zap.Error(errors.WithStack(err)))
 	}
 }
 
-func (x *Xray) Restart() {
-	x.l.Info("xray: restarting...")
+// Restart restarts the core, logging with the given logger (e.g. one carrying the request ID) or the core logger when nil.
+func (x *Xray) Restart(l *logger.Logger) {
+	x.locker.Lock()
+	defer x.locker.Unlock()
 
-	if err := x.Close(); err != nil {
-		x.l.Error("xray: cannot close", zap.Error(errors.WithStack(err)))
+	l = x.logger(l)
+	if err := x.restart(l); err != nil {
+		l.Fatal("xray: cannot run again", zap.Error(errors.WithStack(err)))
 	}
+}
+
+// TryRestart restarts the core unless another run, close or restart is in progress, in which case it returns ErrBusy.
+func (x *Xray) TryRestart(l *logger.Logger) error {
+	return x.TryRestartAfter(l, nil)
+}
+
+// TryRestartAfter runs f (e.g. replacing files the core reads on start) and restarts the core when f succeeds,
+// both while holding the core lock. It returns ErrBusy without running f when another operation is in progress.
+func (x *Xray) TryRestartAfter(l *logger.Logger, f func() error) error {
+	return x.Exclusively(func() error {
+		if f != nil {
+			if err := f(); err != nil {
+				return err
+			}
+		}
+		return x.restart(x.logger(l))
+	})
+}
 
-	if err := x.Run(); err != nil {
-		x.l.Fatal("xray: cannot run again", zap.Error(errors.WithStack(err)))
+func (x *Xray) logger(l *logger.Logger) *logger.Logger {
+	if l == nil {
+		return x.l
 	}
+	return l
 }
 
-func (x *Xray) Close() error {
-	x.l.Debug("xray: closing...")
+// Exclusively runs f while holding the core lock, or returns ErrBusy without running it when the lock is held.
+// The function must not call the locking methods of the core (Run, Close, Restart).
+func (x *Xray) Exclusively(f func() error) error {
+	if !x.locker.TryLock() {
+		return ErrBusy
+	}
+	defer x.locker.Unlock()
+
+	return f()
+}
+
+func (x *Xray) restart(l *logger.Logger) error {
+	l.Info("xray: restarting...")
+
+	if err := x.close(l); err != nil {
+		l.Error("xray: cannot close", zap.Error(errors.WithStack(err)))
+	}
+
+	return errors.WithStack(x.run(l))
+}
 
+func (x *Xray) Close() error {
 	x.locker.Lock()
 	defer x.locker.Unlock()
 
-	if x.connection != nil {
-		x.l.Debug("xray: disconnecting the api connection...")
-		if err := x.connection.Close(); err != nil {
-			x.l.Debug("xray: cannot close the api connection", zap.Error(errors.WithStack(err)))
+	return x.close(x.l)
+}
+
+func (x *Xray) close(l *logger.Logger) error {
+	l.Debug("xray: closing...")
+
+	x.state.Lock()
+	connection, command := x.connection, x.command
+	x.connection, x.command = nil, nil
+	x.state.Unlock()
+
+	if connection != nil {
+		l.Debug("xray: disconnecting the api connection...")
+		if err := connection.Close(); err != nil {
+			l.Debug("xray: cannot close the api connection", zap.Error(errors.WithStack(err)))
 		} else {
-			x.l.Debug("xray: the api connection closed")
+			l.Debug("xray: the api connection closed")
 		}
 	}
 
-	if x.command != nil && x.command.Process != nil {
-		x.l.Debug("xray: killing the process...")
-		if err := x.command.Process.Kill(); err != nil {
+	if command != nil && command.Process != nil {
+		l.Debug("xray: killing the process...")
+		if err := command.Process.Kill(); err != nil {
 			return errors.WithStack(err)
 		} else {
-			x.l.Debug("xray: the process killed")
+			l.Debug("xray: the process killed")
 		}
 	}
 
-	x.l.Info("xray: closed")
+	l.Info("xray: closed")
 	return nil
 }
 
-func (x *Xray) connect() error {
-	x.l.Debug("xray: connecting to api...")
+func (x *Xray) connect(l *logger.Logger) error {
+	l.Debug("xray: connecting to api...")
 
-	inbound := x.config.FindInbound("api")
+	inbound := x.Config().FindInbound("api")
 	if inbound == nil {
 		return errors.New("no inbound inbound")
 	}
@@ -161,7 +254,6 @@ func (x *Xray) connect() error {
 	defer cancel()
 
 	address := "127.0.0.1:" + strconv.Itoa(inbound.Port)
-	var err error
 
 	for {
 		select {
@@ -169,11 +261,14 @@ func (x *Xray) connect() error {
 			return errors.New("connection to Xray api timed out")
 		default:
 			time.Sleep(time.Second)
-			x.connection, err = grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			connection, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
 			if err != nil {
-				x.l.Debug("xray: trying to connect to api", zap.Error(errors.WithStack(err)))
+				l.Debug("xray: trying to connect to api", zap.Error(errors.WithStack(err)))
 			} else {
-				x.l.Debug("xray: connected to api successfully")
+				x.state.Lock()
+				x.connection = connection
+				x.state.Unlock()
+				l.Debug("xray: connected to api successfully")
 				return nil
 			}
 		}
@@ -181,15 +276,92 @@ func (x *Xray) connect() error {
 }
 
 func (x *Xray) Config() *Config {
+	x.state.RLock()
+	defer x.state.RUnlock()
+
 	return x.config
 }
 
-func (x *Xray) SetConfig(config *Config) {
+// SetConfig replaces the config and reports whether it changed, the API services the config depends on are added first,
+// so configs pushed to the node and synced from the manager are run and compared the same way.
+func (x *Xray) SetConfig(config *Config) bool {
+	config.enableRequiredServices()
+
+	x.state.Lock()
+	defer x.state.Unlock()
+
+	changed := !x.config.Equals(config)
 	x.config = config
+	return changed
+}
+
+// Pid returns the process ID of the running core, or zero when it is not running.
+func (x *Xray) Pid() int {
+	x.state.RLock()
+	defer x.state.RUnlock()
+
+	return x.pid()
+}
+
+func (x *Xray) pid() int {
+	if x.command == nil || x.command.Process == nil {
+		return 0
+	}
+	return x.command.Process.Pid
+}
+
+// Status returns the state of the core process.
+func (x *Xray) Status() *Status {
+	x.state.RLock()
+	s := &Status{Pid: x.pid()}
+	s.Running = s.Pid != 0
+	if s.Running {
+		s.StartedAt = x.startedAt.Unix()
+	}
+	x.state.RUnlock()
+
+	s.Version, _ = x.Version()
+	return s
+}
+
+// Version returns the version line reported by the Xray binary, it is cached after the first successful call.
+func (x *Xray) Version() (string, error) {
+	x.state.RLock()
+	version := x.version
+	x.state.RUnlock()
+	if version != "" {
+		return version, nil
+	}
+
+	output, err := exec.Command(x.binaryPath, "version").Output()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	version, _, _ = strings.Cut(strings.TrimSpace(string(output)), "\n")
+
+	x.state.Lock()
+	x.version = version
+	x.state.Unlock()
+	return version, nil
+}
+
+// apiConnection returns the connection to the gRPC API of the running core.
+func (x *Xray) apiConnection() (*grpc.ClientConn, error) {
+	x.state.RLock()
+	defer x.state.RUnlock()
+
+	if x.connection == nil {
+		return nil, errors.New("xray: not connected to the api")
+	}
+	return x.connection, nil
 }
 
 func (x *Xray) QueryStats() ([]*stats.Stat, error) {
-	client := stats.NewStatsServiceClient(x.connection)
+	connection, err := x.apiConnection()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	client := stats.NewStatsServiceClient(connection)
 	qs, err := client.QueryStats(context.Background(), &stats.QueryStatsRequest{Reset_: true})
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -205,5 +377,6 @@ func New(c context.Context, logger *logger.Logger, logLevel, configPath, binaryP
 		binaryPath: binaryPath,
 		configPath: configPath,
 		locker:     &sync.Mutex{},
+		state:      &sync.RWMutex{},
 	}
 }
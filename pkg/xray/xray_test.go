@@ -0,0 +1,106 @@
+package xray
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ebadidev/arch-node/pkg/logger"
+)
+
+// fakeBinary prints a version or waits until it is killed, like the core does.
+const fakeBinary = `#!/bin/sh
+if [ "$1" = "version" ]; then
+  echo "Xray 25.6.8 (Xray, Penetrates Everything.) Custom (go1.24.4 linux/amd64)"
+  echo "A unified platform for anti-censorship."
+  exit 0
+fi
+exec sleep 60
+`
+
+func newTestXray(t *testing.T) *Xray {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("storage/logs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	l := logger.New("error", "2006-01-02 15:04:05", make(chan struct{}, 10))
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	binary, err := filepath.Abs("xray")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(binary, []byte(fakeBinary), 0755); err != nil {
+		t.Fatal(err)
+	}
+	x := New(context.Background(), l, "info", "xray.json", binary)
+	t.Cleanup(func() {
+		_ = x.Close()
+	})
+	return x
+}
+
+func TestStatusDuringRestart(t *testing.T) {
+	x := newTestXray(t)
+	if s := x.Status(); s.Running || s.Pid != 0 {
+		t.Errorf("Expected the core not to be running, got %+v", s)
+	}
+
+	if err := x.Run(); err != nil {
+		t.Fatalf("Unexpected run error: %v", err)
+	}
+	s := x.Status()
+	if !s.Running || s.Pid == 0 || s.StartedAt == 0 {
+		t.Errorf("Expected the core to be running, got %+v", s)
+	}
+	if s.Version != "Xray 25.6.8 (Xray, Penetrates Everything.) Custom (go1.24.4 linux/amd64)" {
+		t.Errorf("Unexpected version %q", s.Version)
+	}
+
+	// The status is read by the API while restarts and config updates run, go test -race checks them.
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					x.Status()
+					x.Pid()
+					x.Config().FindInbound("api")
+					_, _ = x.QueryStats()
+				}
+			}
+		}()
+	}
+	x.SetConfig(NewConfig("warning"))
+	x.Restart(nil)
+	close(done)
+	wg.Wait()
+
+	if restarted := x.Status(); !restarted.Running || restarted.Pid == s.Pid {
+		t.Errorf("Expected a new core process, got %+v", restarted)
+	}
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if x.Status().Running {
+		t.Error("Expected the core to be stopped")
+	}
+}
+
+func TestRunWithoutBinary(t *testing.T) {
+	x := newTestXray(t)
+	x.binaryPath = filepath.Join(t.TempDir(), "missing")
+	if err := x.Run(); err == nil {
+		t.Error("Expected an error without the binary")
+	}
+}
@@ -1,9 +1,13 @@
 #!/bin/bash
 
 DB_PATH="$(realpath "$(dirname "$0")/../storage/database/app.json")"
+CERT_PATH="$(realpath "$(dirname "$0")/../storage/app/http.crt")"
 if [ -f "$DB_PATH" ]; then
   printf "IP: " && curl ifconfig.io
   printf "DB: " && cat "$DB_PATH" && printf "\n"
+  if [ -f "$CERT_PATH" ]; then
+    printf "TLS: " && openssl x509 -noout -fingerprint -sha256 -in "$CERT_PATH"
+  fi
 else
   echo "The app is not ready yet. Please try again..."
 fi
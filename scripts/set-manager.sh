@@ -10,13 +10,19 @@ TOKEN="$2"
 HTTP_TOKEN=$(jq -r '.settings.http_token' "$DB_PATH")
 HTTP_PORT=$(jq -r '.settings.http_port' "$DB_PATH")
 
+# Detect whether the node API serves HTTPS
+SCHEME="http"
+if curl -sk -o /dev/null "https://localhost:$HTTP_PORT/"; then
+    SCHEME="https"
+fi
+
 # Make the HTTP request
 echo "Setting manager with URL: $URL"
-if curl -s -X POST \
+if curl -sk -X POST \
     -H "Content-Type: application/json" \
     -H "Authorization: Bearer $HTTP_TOKEN" \
     -d "{\"url\":\"$URL\",\"token\":\"$TOKEN\"}" \
-    "http://localhost:$HTTP_PORT/v1/manager"; then
+    "$SCHEME://localhost:$HTTP_PORT/v1/manager"; then
     echo "Manager configs updated successfully"
 else
     echo "Failed to update manager configs"